@@ -3,9 +3,14 @@ package main
 
 import (
 	com_utils "Encrypted_Cartpole/03_Utils"
+	"Encrypted_Cartpole/03_Utils/pid"
+	"Encrypted_Cartpole/03_Utils/protocol"
+	"Encrypted_Cartpole/03_Utils/spec"
 	"bufio"
-	"encoding/csv"
+	"bytes"
+	"crypto/tls"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"math"
@@ -13,13 +18,10 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
 	"go.bug.st/serial"
 
-	utils "github.com/CDSL-EncryptedControl/CDSL/utils"
-	RLWE "github.com/CDSL-EncryptedControl/CDSL/utils/core/RLWE"
 	"github.com/tuneinsight/lattigo/v6/core/rlwe"
 )
 
@@ -29,44 +31,33 @@ const (
 	// addr       = "192.168.20.133:8080" // TCP 컨트롤러 주소
 	serialPort = "/dev/ttyACM0"
 	baudRate   = 115200
-
-	// RLWE params
-	logN = 12
-	logQ = 56
-	logP = 51
-
-	// 차원
-	n = 4
-	m = 1
-	p = 2
-
-	// 양자화 스케일
-	s = 1.0 / 10.0
-	L = 1.0 / 10000.0
-	r = 1.0 / 1000.0
 )
 
-// PID 계수
-// const (
-// 	Kp = 32.0
-// 	Ki = 2.7
-// 	Kd = 42.0
+// 제어기 spec (offline keygen / controller 와 같은 파일) & artifact 폴더
+var (
+	specPath    = flag.String("spec", filepath.Join("..", "config", "cartpole_N12.json"), "controller spec file")
+	artifactDir = flag.String("dir", filepath.Join("..", "02_Offline_task", "enc_data", "rgsw_for_N12", "plant"), "plant bundle written by keygen (sk + scales)")
+	unsafeSeed  = flag.String("unsafe-seed", "", "UNSAFE, tests only: deterministic y ciphertext stream from this seed")
+	bundleID    = flag.String("id", "", "controller bundle id to use when the controller serves several (keygen -out folder name)")
 
-// 	Lp = 30.0
-// 	Li = 0.6
-// 	Ld = 7.0
-// )
+	// 아두이노는 25 ms 안에 u 가 안 오면 이전 u 를 유지함 → plant 도 같은 창 안에서 포기하고 대체 u 를 보냄
+	readTimeout  = flag.Duration("read-timeout", 25*time.Millisecond, "wait this long after sending y for its u (0 = forever)")
+	writeTimeout = flag.Duration("write-timeout", 25*time.Millisecond, "deadline for sending y (0 = none)")
+	fallback     = flag.String("fallback", "hold", "u on a deadline miss: hold (last encrypted u) or shadow (local plaintext PID)")
 
-	const (
-		Kp = 32.0
-		Ki = 2.5
-		Kd = 42.0
+	// 상호 인증 TLS (셋 다 비우면 평문 TCP), certs.go 로 발급
+	tlsFiles      = protocol.TLSFiles{}
+	tlsServerName = flag.String("tls-server-name", "", "name or IP in the controller certificate (default: host of addr)")
 
-		Lp = 30.0
-		Li = 0.7
-		Ld = 7.0
-	)
+	// udp: 조각 단위 datagram, 잃은 u 는 deadline miss 와 같게 hold / shadow (protocol/udp.go)
+	transport = flag.String("transport", "tcp", "controller link: tcp (optionally with -tls-*) or udp")
+)
 
+func init() {
+	flag.StringVar(&tlsFiles.CA, "tls-ca", "", "lab CA certificate; with -tls-cert/-tls-key, connect with mutual TLS and accept only a controller certificate from this CA")
+	flag.StringVar(&tlsFiles.Cert, "tls-cert", "", "plant certificate (certs.go: <plant>.crt)")
+	flag.StringVar(&tlsFiles.Key, "tls-key", "", "plant private key (certs.go: <plant>.key)")
+}
 
 // ===== 안전 임계치 & 루프 횟수 =====
 const (
@@ -75,58 +66,163 @@ const (
 	maxIter       = 0     // 0=무한루프, 양수=그 횟수만큼만 실행
 )
 
-// 상태공간 행렬
-var C = []float64{Ki, -Kd, Li, -Ld}
-var D = []float64{Kp + Ki + Kd, Lp + Li + Ld}
+// 평문 shadow 제어기 (spec → pid 패키지, 암호 제어기와 같은 realization)
+var shadow *pid.Controller
 
-var state = []float64{0, 0, 0, 0}
 var y = []float64{0, 0}
 
-// ---- 유틸: "a,b" 파싱 ----
-func parseTwoFloats(line string) (float64, float64, error) {
-	line = strings.TrimSpace(line)
-	if line == "" {
+// ---- 유틸: "a,b" 파싱 (Scanner 버퍼 그대로, 세 번째 필드부터는 무시) ----
+func parseTwoFloats(line []byte) (float64, float64, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
 		return 0, 0, errors.New("empty line")
 	}
-	parts := strings.SplitN(line, ",", 3)
-	if len(parts) < 2 {
+	i := bytes.IndexByte(line, ',')
+	if i < 0 {
 		return 0, 0, fmt.Errorf("malformed: %q", line)
 	}
-	a0, err0 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
-	a1, err1 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	f0, f1 := line[:i], line[i+1:]
+	if j := bytes.IndexByte(f1, ','); j >= 0 {
+		f1 = f1[:j]
+	}
+	a0, err0 := strconv.ParseFloat(string(bytes.TrimSpace(f0)), 64)
+	a1, err1 := strconv.ParseFloat(string(bytes.TrimSpace(f1)), 64)
 	if err0 != nil || err1 != nil {
 		return 0, 0, fmt.Errorf("parse float failed: %v %v (line=%q)", err0, err1, line)
 	}
 	return a0, a1, nil
 }
 
-// ---- CSV 저장 ----
-func saveCSV(path string, rows [][]string) error {
+// 스텝마다 찍는 로그 줄 (fmt.Printf 는 float 인자마다 할당 → 같은 형식을 버퍼에 붙여서 한 줄씩 출력)
+type lineBuf []byte
+
+func (b *lineBuf) s(str string) *lineBuf { *b = append(*b, str...); return b }
+
+// %.<prec>f
+func (b *lineBuf) f(v float64, prec int) *lineBuf {
+	*b = strconv.AppendFloat(*b, v, 'f', prec, 64)
+	return b
+}
+
+func (b *lineBuf) flush() {
+	os.Stdout.Write(*b)
+	*b = (*b)[:0]
+}
+
+// CSV 한 줄
+type stepRecord struct {
+	iter                     int
+	elapsedMs, y0, y1        float64
+	uLocal, uRemote, uOut    float64
+	uDiff, intervalMs, rttMs float64
+	clamped                  bool
+	seq                      uint64
+	stale, staleTotal        int
+	uSource                  string
+	missTotal                int
+}
+
+const csvHeader = "iter,t_ms,y0_angle,y1_position,uLocal,uRemote,uOut,uDiff,loopIntervalMs,tcpRttMs,clamped,seq,staleU,staleTotal,uSource,missTotal\n"
+
+// data.csv 를 스텝마다 바로 씀 (줄 버퍼 재사용 → 할당 없음, 실행 길이와 상관없이 메모리 일정)
+// 값에 쉼표 / 따옴표가 없으므로 encoding/csv 의 따옴표 처리 없이 그대로
+type csvLog struct {
+	f    *os.File
+	w    *bufio.Writer
+	line lineBuf
+	rows int
+}
+
+func createCSV(path string) (*csvLog, error) {
 	f, err := os.Create(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer f.Close()
-
-	w := csv.NewWriter(f)
-	defer w.Flush()
+	c := &csvLog{f: f, w: bufio.NewWriterSize(f, 64<<10), line: make(lineBuf, 0, 256)}
+	if _, err := c.w.WriteString(csvHeader); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
 
-	header := []string{
-		"iter", "t_ms",
-		"y0_angle", "y1_position",
-		"uLocal", "uRemote", "uOut", "uDiff",
-		"loopIntervalMs", "tcpRttMs",
-		"clamped",
+func (c *csvLog) write(r *stepRecord) error {
+	b := c.line[:0]
+	b = strconv.AppendInt(b, int64(r.iter), 10)
+	for _, v := range [...]float64{r.elapsedMs, r.y0, r.y1, r.uLocal, r.uRemote, r.uOut, r.uDiff, r.intervalMs, r.rttMs} {
+		b = append(b, ',')
+		b = strconv.AppendFloat(b, v, 'f', 3, 64)
 	}
-	if err := w.Write(header); err != nil {
+	b = append(b, ',')
+	b = append(b, boolTo01(r.clamped)...)
+	b = append(b, ',')
+	b = strconv.AppendUint(b, r.seq, 10)
+	b = append(b, ',')
+	b = strconv.AppendInt(b, int64(r.stale), 10)
+	b = append(b, ',')
+	b = strconv.AppendInt(b, int64(r.staleTotal), 10)
+	b = append(b, ',')
+	b = append(b, r.uSource...)
+	b = append(b, ',')
+	b = strconv.AppendInt(b, int64(r.missTotal), 10)
+	b = append(b, '\n')
+	c.line = b
+	if _, err := c.w.Write(b); err != nil {
 		return err
 	}
-	for _, r := range rows {
-		if err := w.Write(r); err != nil {
-			return err
+	c.rows++
+	return nil
+}
+
+// 남은 줄을 쓰고 닫음 (한 줄도 없으면 파일을 지움)
+func (c *csvLog) Close() error {
+	err := c.w.Flush()
+	if cerr := c.f.Close(); err == nil {
+		err = cerr
+	}
+	if c.rows == 0 {
+		os.Remove(c.f.Name())
+	}
+	return err
+}
+
+// 이번 스텝 u 를 못 받은 경우 (ERROR, CRC 실패, 디코딩 실패) — 세션은 유지
+var errSkipStep = errors.New("no u for this step")
+
+// seq 와 같은 seq 의 U 가 올 때까지 읽음
+// 예전 y 에 대한 U / ERROR (타임아웃, 끊김 뒤에 늦게 도착한 것) 는 적용하지 않고 버린 개수만 반환
+// deadline 이 지나면 protocol.IsTimeout 에러 (이번 seq 의 u 가 나중에 오면 다음 스텝에서 stale 로 버려짐)
+// u 는 uWire 의 암호문에 읽음 (스텝마다 재사용)
+func recvU(pc protocol.Transport, seq uint64, deadline time.Time, uWire *com_utils.CtWire) (*rlwe.Ciphertext, int, error) {
+	stale := 0
+	for {
+		frame, _, err := pc.RecvUntil(deadline)
+		if errors.Is(err, protocol.ErrChecksum) {
+			return nil, stale, fmt.Errorf("%w: %v", errSkipStep, err)
+		}
+		if err != nil {
+			return nil, stale, err
+		}
+		switch frame.Type {
+		case protocol.TypeU, protocol.TypeError:
+			if frame.Seq != seq {
+				stale++
+				log.Printf("[Combined] drop stale %s (seq %d, want %d)", frame.Type, frame.Seq, seq)
+				continue
+			}
+			if frame.Type == protocol.TypeError {
+				return nil, stale, fmt.Errorf("%w: %v", errSkipStep, frame.Err())
+			}
+			if err := frame.Decode(uWire); err != nil {
+				return nil, stale, fmt.Errorf("%w: %v", errSkipStep, err)
+			}
+			return uWire.Ct, stale, nil
+		case protocol.TypeBye:
+			return nil, stale, errors.New("controller closed the session (BYE)")
+		default:
+			log.Printf("[Combined] ignore unexpected %s frame (seq %d)", frame.Type, frame.Seq)
 		}
 	}
-	return w.Error()
 }
 
 func boolTo01(b bool) string {
@@ -137,35 +233,136 @@ func boolTo01(b bool) string {
 }
 
 func main() {
-	// ===== RLWE 세팅 =====
-	params, _ := rlwe.NewParametersFromLiteral(rlwe.ParametersLiteral{
-		LogN:    logN,
-		LogQ:    []int{logQ},
-		LogP:    []int{logP},
-		NTTFlag: true,
-	})
-	ringQ := params.RingQ()
-
-	maxDim := math.Max(math.Max(float64(n), float64(m)), float64(p))
-	tau := int(math.Pow(2, math.Ceil(math.Log2(maxDim))))
-
-	base := filepath.Join("..", "02_Offline_task", "enc_data", "rgsw_for_N12")
-	sk := new(rlwe.SecretKey)
-	if err := com_utils.ReadRT(filepath.Join(base, "sk.dat"), sk); err != nil {
+	flag.Parse()
+	// 결정적 모드: y 암호화 sampler 에만 seed (TLS 는 crypto/rand 그대로)
+	var seed *com_utils.UnsafeSeed
+	if *unsafeSeed != "" {
+		s, err := com_utils.NewUnsafeSeed(*unsafeSeed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		seed = s
+		log.Println(com_utils.UnsafeSeedWarning)
+	}
+
+	// ===== Controller spec =====
+	base := *artifactDir
+	sp, err := spec.Load(*specPath)
+	if err != nil {
+		log.Fatalf("load spec: %v", err)
+	}
+	// artifact 를 만든 spec 과 다르면 시작하지 않음 (manifest 기준)
+	bundle, err := com_utils.OpenPlantBundle(base)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := bundle.CheckSpec(sp); err != nil {
+		log.Fatal(err)
+	}
+	if *fallback != "hold" && *fallback != "shadow" {
+		log.Fatalf("-fallback: want hold or shadow, got %q", *fallback)
+	}
+	if bundle.Manifest.UnsafeSeed {
+		log.Printf("%s (bundle %s)", com_utils.UnsafeSeedWarning, base)
+	}
+	shadow, err = pid.NewController(sp.StateSpace(), sp.XIni)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, m, _ := sp.Dims()
+
+	// ===== RLWE 세팅 (y 암호화 / u 복호화, 버퍼는 codec 안에서 재사용) =====
+	// 비밀키 잠금 해제 (환경변수 CARTPOLE_SK_PASSPHRASE 또는 프롬프트)
+	passphrase, err := com_utils.Passphrase("Plant secret key passphrase: ", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	codec, err := bundle.LoadPlantCodec(passphrase)
+	if err != nil {
 		log.Fatalf("load sk: %v", err)
 	}
-	encryptor := rlwe.NewEncryptor(params, sk)
-	decryptor := rlwe.NewDecryptor(params, sk)
+	if err := codec.UseUnsafeSeed(seed); err != nil {
+		log.Fatal(err)
+	}
 
-	// ===== TCP 연결 =====
-	conn, err := net.Dial("tcp", addr)
+	// ===== controller 연결 (TCP / TLS / UDP) =====
+	var pc protocol.Transport
+	var udp *protocol.UDPConn
+	switch *transport {
+	case "tcp":
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			log.Fatalf("tcp dial: %v", err)
+		}
+		defer conn.Close()
+		if tlsFiles.Enabled() {
+			name := *tlsServerName
+			if name == "" {
+				name = protocol.ServerName(addr)
+			}
+			conf, err := protocol.ClientTLS(tlsFiles, name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			tc := tls.Client(conn, conf)
+			cn, err := protocol.Handshake(tc, 5*time.Second)
+			if err != nil {
+				log.Fatal(err)
+			}
+			conn = tc
+			fmt.Printf("[Combined] TLS controller %q\n", cn)
+		}
+		tcp := protocol.NewConn(conn)
+		tcp.WriteTimeout = *writeTimeout
+		pc = tcp
+	case "udp":
+		if tlsFiles.Enabled() {
+			log.Fatal("-tls-*: mutual TLS needs -transport tcp (DTLS is not supported)")
+		}
+		udp, err = protocol.DialUDP(addr)
+		if err != nil {
+			log.Fatalf("udp: %v", err)
+		}
+		defer udp.Close()
+		pc = udp
+	default:
+		log.Fatalf("-transport: want tcp or udp, got %q", *transport)
+	}
+	fmt.Println("[Combined] Connected to controller:", addr, *transport)
+	// u 프레임은 bundle 파라미터의 암호문 크기까지만 받음
+	_, uLimit := com_utils.FrameLimits(bundle.Manifest.Params)
+	pc.SetMaxPayload(protocol.TypeU, uLimit)
+
+	// HELLO: controller 가 같은 spec 의 bundle 을 쓰는지 확인 (udp 는 잃을 수 있으므로 1 s 마다 다시 보냄)
+	tries, wait := 1, time.Duration(0)
+	if udp != nil {
+		tries, wait = 5, time.Second
+	}
+	var hello *protocol.Frame
+	for i := 0; i < tries; i++ {
+		if _, err := pc.SendHello(protocol.Hello{Role: com_utils.RolePlant, SpecSHA256: bundle.Manifest.SpecSHA256, BundleID: *bundleID}); err != nil {
+			log.Fatalf("send HELLO: %v", err)
+		}
+		var deadline time.Time
+		if wait > 0 {
+			deadline = time.Now().Add(wait)
+		}
+		hello, _, err = pc.RecvUntil(deadline)
+		if !protocol.IsTimeout(err) {
+			break
+		}
+	}
 	if err != nil {
-		log.Fatalf("tcp dial: %v", err)
+		log.Fatalf("read HELLO: %v", err)
+	}
+	switch hello.Type {
+	case protocol.TypeHello:
+	case protocol.TypeError:
+		log.Fatal(hello.Err())
+	default:
+		log.Fatalf("want HELLO, got %s", hello.Type)
 	}
-	defer conn.Close()
-	rbuf := bufio.NewReader(conn)
-	wbuf := bufio.NewWriter(conn)
-	fmt.Println("[Combined] Connected to controller:", addr)
 
 	// ===== 시리얼 오픈 =====
 	mode := &serial.Mode{BaudRate: baudRate}
@@ -189,12 +386,30 @@ func main() {
 	csvPath := filepath.Join(outDir, "data.csv") // ← 항상 같은 이름으로 저장
 	// ▲▲▲ 변경 끝 ▲▲▲
 
-	records := make([][]string, 0, 4096)
+	csvOut, err := createCSV(csvPath)
+	if err != nil {
+		log.Fatalf("csv: %v", err)
+	}
 	fmt.Println("[CSV] Logging to:", csvPath)
 
 	var lastTime time.Time
 	iter := 0
 
+	// y/u 에 붙는 반복 번호 (HELLO 가 0 이므로 1 부터, 건너뛴 스텝에서도 증가)
+	var seq uint64
+	staleTotal := 0
+
+	// deadline 을 놓친 스텝 수, 마지막으로 받은 암호 u (hold 용)
+	missTotal := 0
+	lastU := 0.0
+
+	// 스텝마다 재사용하는 버퍼 (seed 압축 y, u 암호문, 복호화 / shadow 결과, 출력 줄)
+	yCtPack := codec.NewSeeded()
+	uWire := new(com_utils.CtWire)
+	uVec := make([]float64, m)
+	uShadow := make([]float64, m)
+	var out, serialLine lineBuf
+
 	for {
 		// 1) Arduino에서 y 읽기 (angle=y[0], position=y[1] 가정)
 		if !sc.Scan() {
@@ -205,8 +420,7 @@ func main() {
 			}
 			break
 		}
-		line := sc.Text()
-		y0, y1, err := parseTwoFloats(line)
+		y0, y1, err := parseTwoFloats(sc.Bytes())
 		if err != nil {
 			log.Printf("[Combined] skip bad line: %v", err)
 			continue
@@ -219,60 +433,73 @@ func main() {
 		intervalMs := 0.0
 		if !lastTime.IsZero() {
 			intervalMs = float64(now.Sub(lastTime)) / 1e6
-			fmt.Printf("[Loop] interval: %.3f ms\n", intervalMs)
+			out.s("[Loop] interval: ").f(intervalMs, 3).s(" ms\n").flush()
 		}
 		lastTime = now
 
-		// 2) 로컬 제어 입력 계산
-		uLocal := C[0]*state[0] + C[1]*state[1] + C[2]*state[2] + C[3]*state[3] +
-			D[0]*y[0] + D[1]*y[1]
-
-		// 3) 상태 업데이트
-		state[0] += y[0]
-		state[1] = y[0]
-		state[2] += y[1]
-		state[3] = y[1]
+		// 2) 로컬 제어 입력 계산 + 3) 상태 업데이트
+		shadow.StepInto(y, uShadow)
+		uLocal := uShadow[0]
 
-		// 4) y → 암호화 후 컨트롤러로 송신
-		yBar := utils.RoundVec(utils.ScalVecMult(1.0/r, y))
-		yCtPack := RLWE.EncPack(yBar, tau, 1.0/L, *encryptor, ringQ, params)
+		// 4) y → 암호화 후 컨트롤러로 송신 (seed 압축: a 대신 32바이트 seed 를 보냄)
+		if err := codec.EncryptYSeededInto(y, yCtPack); err != nil {
+			log.Printf("[Combined] Encrypt y err: %v", err)
+			break
+		}
 
 		// 🔹 RTT 측정 시작: y 보내고 u 받을 때까지
 		tStart := time.Now()
 
-		if _, err := yCtPack.WriteTo(wbuf); err != nil {
+		seq++
+		_, err = pc.Send(protocol.TypeY, seq, yCtPack)
+		if err != nil && !protocol.IsTimeout(err) {
 			log.Printf("[Combined] Write yCtPack err: %v", err)
 			break
 		}
-		if err := wbuf.Flush(); err != nil {
-			log.Printf("[Combined] Flush err: %v", err)
-			break
-		}
 
-		// 컨트롤러 응답 수신
-		uCtPack := new(rlwe.Ciphertext)
-		if _, err := uCtPack.ReadFrom(rbuf); err != nil {
+		// 컨트롤러 응답 수신 (이번 seq 의 u 만 적용, read-timeout 안에 못 받으면 대체 u)
+		var uCtPack *rlwe.Ciphertext
+		stale := 0
+		if err == nil {
+			deadline := time.Time{}
+			if *readTimeout > 0 {
+				deadline = tStart.Add(*readTimeout)
+			}
+			uCtPack, stale, err = recvU(pc, seq, deadline, uWire)
+			staleTotal += stale
+		}
+		if err != nil && !protocol.IsTimeout(err) && !errors.Is(err, errSkipStep) {
 			log.Printf("[Combined] Read uCtPack err: %v", err)
 			break
 		}
 
 		// 🔹 RTT (ms)
 		rttMs := float64(time.Since(tStart)) / 1e6
-		fmt.Printf("[Latency] TCP round-trip: %.3f ms\n", rttMs)
+		out.s("[Latency] TCP round-trip: ").f(rttMs, 3).s(" ms\n").flush()
 
-		// 5) 복호화 및 스케일 복원
-		uVec := RLWE.DecUnpack(uCtPack, m, tau, *decryptor, r*s*s*L, ringQ, params)
+		// 5) 복호화 및 스케일 복원 (못 받았으면 hold: 이전 u / shadow: 평문 PID)
 		uRemote := 0.0
-		if len(uVec) > 0 {
+		uSource := "enc"
+		if uCtPack != nil {
+			codec.DecryptUInto(uCtPack, uVec)
 			uRemote = uVec[0]
+			lastU = uRemote
+		} else {
+			missTotal++
+			uSource = *fallback
+			uRemote = lastU
+			if *fallback == "shadow" {
+				uRemote = uLocal
+			}
+			log.Printf("[Combined] no u for seq %d: %v → %s u=%.6f (misses %d)", seq, err, uSource, uRemote, missTotal)
 		}
 
 		// == 디버그 3종 한 줄 출력 ==
-		fmt.Printf("[DEBUG] RTT=%.3f ms | uLocal=%.6f | uRecv=%.6f\n", rttMs, uLocal, uRemote)
+		out.s("[DEBUG] RTT=").f(rttMs, 3).s(" ms | uLocal=").f(uLocal, 6).s(" | uRecv=").f(uRemote, 6).s("\n").flush()
 
 		// 6) 두 제어 입력 비교 출력
 		uDiff := uLocal - uRemote
-		fmt.Printf("[Compare] uLocal=%.6f | uRemote=%.6f | Δ=%.6f\n", uLocal, uRemote, uDiff)
+		out.s("[Compare] uLocal=").f(uLocal, 6).s(" | uRemote=").f(uRemote, 6).s(" | Δ=").f(uDiff, 6).s("\n").flush()
 
 		// 7) 안전 로직: |angle|>40 또는 |position|>200 이면 u=0
 		angle := y[0]
@@ -282,32 +509,42 @@ func main() {
 		if math.Abs(angle) > angleLimit || math.Abs(position) > positionLimit {
 			uOut = 0.0
 			clamped = true
-			fmt.Printf("[SAFEGUARD] |angle|=%.3f, |position|=%.3f beyond (%.1f, %.1f) → u=0 sent.\n",
-				math.Abs(angle), math.Abs(position), angleLimit, positionLimit)
+			out.s("[SAFEGUARD] |angle|=").f(math.Abs(angle), 3).s(", |position|=").f(math.Abs(position), 3).
+				s(" beyond (").f(angleLimit, 1).s(", ").f(positionLimit, 1).s(") → u=0 sent.\n").flush()
 		}
 
 		// 8) 실제로 아두이노에 보낼 것은 uOut
-		if _, err := port.Write([]byte(fmt.Sprintf("%.6f\n", uOut))); err != nil {
+		serialLine = serialLine[:0]
+		serialLine.f(uOut, 6).s("\n")
+		if _, err := port.Write(serialLine); err != nil {
 			log.Printf("[Combined] Serial write err: %v", err)
 			break
 		}
 
 		// 9) 로깅 (CSV용) — 포맷/내용 유지
 		elapsedMs := float64(time.Since(startT)) / 1e6
-		record := []string{
-			strconv.Itoa(iter),
-			fmt.Sprintf("%.3f", elapsedMs),
-			fmt.Sprintf("%.3f", y[0]),
-			fmt.Sprintf("%.3f", y[1]),
-			fmt.Sprintf("%.3f", uLocal),
-			fmt.Sprintf("%.3f", uRemote),
-			fmt.Sprintf("%.3f", uOut),
-			fmt.Sprintf("%.3f", uDiff),
-			fmt.Sprintf("%.3f", intervalMs),
-			fmt.Sprintf("%.3f", rttMs),
-			boolTo01(clamped),
+		rec := stepRecord{
+			iter:       iter,
+			elapsedMs:  elapsedMs,
+			y0:         y[0],
+			y1:         y[1],
+			uLocal:     uLocal,
+			uRemote:    uRemote,
+			uOut:       uOut,
+			uDiff:      uDiff,
+			intervalMs: intervalMs,
+			rttMs:      rttMs,
+			clamped:    clamped,
+			seq:        seq,
+			stale:      stale,
+			staleTotal: staleTotal,
+			uSource:    uSource,
+			missTotal:  missTotal,
+		}
+		if err := csvOut.write(&rec); err != nil {
+			log.Printf("[CSV] write err: %v", err)
+			break
 		}
-		records = append(records, record)
 
 		iter++
 		if maxIter > 0 && iter >= maxIter {
@@ -316,15 +553,22 @@ func main() {
 		}
 	}
 
-	// 종료 시 CSV 저장
-	if len(records) == 0 {
-		fmt.Println("[CSV] No data collected.")
-		return
+	pc.SendBytes(protocol.TypeBye, seq, nil) // controller 가 먼저 끊었으면 실패해도 무시
+	// TCP 와 비교용: 스텝 중 u 를 못 받은 비율 (udp 는 조각 단위 손실도)
+	fmt.Printf("[Combined] %s: %d steps, u misses %d (%.2f%%), stale %d\n", *transport, seq, missTotal, 100*float64(missTotal)/float64(max(seq, 1)), staleTotal)
+	if udp != nil {
+		fmt.Printf("[Combined] udp u %s\n", udp.Stats())
 	}
-	if err := saveCSV(csvPath, records); err != nil {
+
+	// 종료 시 남은 CSV 줄 저장
+	rows := csvOut.rows
+	if err := csvOut.Close(); err != nil {
 		log.Printf("[CSV] Save error: %v", err)
+	} else if rows == 0 {
+		fmt.Println("[CSV] No data collected.")
+		return
 	} else {
-		fmt.Printf("[CSV] Saved %d rows to %s\n", len(records), csvPath)
+		fmt.Printf("[CSV] Saved %d rows to %s\n", rows, csvPath)
 	}
 	fmt.Println("[Combined] Stopped.")
 }
@@ -2,33 +2,111 @@ package main
 
 import (
 	com_utils "Encrypted_Cartpole/03_Utils"
-	"bufio"
+	"Encrypted_Cartpole/03_Utils/metrics"
+	"Encrypted_Cartpole/03_Utils/protocol"
+	"Encrypted_Cartpole/03_Utils/spec"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"math"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	rtmetrics "runtime/metrics"
+	"strconv"
+	"sync"
 	"time"
 
-	RGSW "github.com/CDSL-EncryptedControl/CDSL/utils/core/RGSW"
-	RLWE "github.com/CDSL-EncryptedControl/CDSL/utils/core/RLWE"
-	"github.com/tuneinsight/lattigo/v6/core/rgsw"
 	"github.com/tuneinsight/lattigo/v6/core/rlwe"
-	"github.com/tuneinsight/lattigo/v6/ring"
 )
 
 const (
 	// addr = "192.168.0.20:8080" // 서버 바인딩 주소
 	// addr     = "192.168.20.133:8080" // 서버 바인딩 주소
 	addr     = ":8080" // 서버 바인딩 주소
-	numIters = 1000    // 0 means infinite loop
+	numIters = 0       // 세션당 최대 스텝, 0 = plant 가 끊을 때까지
 	period   = 0 * time.Millisecond
 
 	printEvery = 10 // ★ 매 100회마다 요약 출력
+
+	handshakeTimeout = 5 * time.Second // TLS handshake (인증서 확인) 제한 시간
+)
+
+var (
+	specPath    = flag.String("spec", filepath.Join("..", "config", "cartpole_N12.json"), "controller spec file")
+	artifactDir = flag.String("dir", filepath.Join("..", "02_Offline_task", "enc_data", "rgsw_for_N12", "controller"), "controller bundle written by keygen (must not contain sk)")
+	bundlesDir  = flag.String("bundles", "", "serve every <root>/<id>/controller bundle (keygen -out <root>/<id>), plants pick one by HELLO id; overrides -spec/-dir")
+
+	// 읽기: plant 가 y 를 안 보내는 시간 (놓쳐도 계속 대기, 횟수만 셈) / 쓰기: u 송신 (놓치면 plant 가 hold/shadow)
+	readTimeout  = flag.Duration("read-timeout", time.Second, "log a miss when no y arrives within this (0 = wait silently)")
+	writeTimeout = flag.Duration("write-timeout", 25*time.Millisecond, "deadline for sending u (0 = none)")
+
+	// udp: 조각 단위 datagram, 잃은 y 는 read miss (protocol/udp.go)
+	transport = flag.String("transport", "tcp", "plant link: tcp (optionally with -tls-*) or udp")
+	udpIdle   = flag.Duration("udp-idle", 10*time.Second, "udp: end a session after this long without any datagram from the plant")
+
+	// u 를 보낸 뒤 x ← Fx + Gy 를 다음 y 를 기다리는 동안 계산 (다음 Unpack 전에 끝날 때까지 기다림)
+	pipeline = flag.Bool("pipeline", true, "overlap the state update with waiting for the next y (false = strictly serial loop)")
+
+	// Output / Update 의 외부곱을 나눠 계산할 goroutine 수, 모든 세션이 같이 씀 (Kernel 과 버퍼는 세션마다 worker 수만큼)
+	workers = flag.Int("workers", 0, "external-product goroutines shared by all sessions (0 = number of CPUs, 1 = serial); results are bit-identical")
+
+	metricsAddr = flag.String("metrics", "", "serve Prometheus metrics at http://<addr>/metrics, e.g. 127.0.0.1:9100 (empty = off)")
+
+	// 상호 인증 TLS (셋 다 비우면 평문 TCP), certs.go 로 발급
+	tlsFiles = protocol.TLSFiles{}
 )
 
+func init() {
+	flag.StringVar(&tlsFiles.CA, "tls-ca", "", "lab CA certificate; with -tls-cert/-tls-key, only plants holding a certificate from this CA are accepted")
+	flag.StringVar(&tlsFiles.Cert, "tls-cert", "", "controller certificate (certs.go: controller.crt)")
+	flag.StringVar(&tlsFiles.Key, "tls-key", "", "controller private key (certs.go: controller.key)")
+}
+
 func ms(d time.Duration) float64 { return float64(d) / 1e6 }
 
+// ======== Prometheus metrics (-metrics) ========
+// 라벨: session = 세션 번호, params = bundle 의 파라미터 (예: N12), 창 출력과 같은 값을 스텝마다 기록
+// 세션 series 는 세션이 끝나면 지움 (series 수가 연결 수만큼 늘지 않게), 끝난 세션 / 거부된 연결은 session="" 누적값으로만
+var (
+	reg = metrics.NewRegistry()
+
+	mPhase    = reg.Histogram("cartpole_controller_phase_seconds", "Controller loop phase latency (recv includes waiting for y; loop = whole step).", metrics.LatencyBuckets, "session", "params", "phase")
+	mGap      = reg.Histogram("cartpole_controller_send_to_recv_seconds", "Time from sending u to receiving the next y (network + plant decrypt/serial/encrypt).", metrics.LatencyBuckets, "session", "params")
+	mBytes    = reg.Counter("cartpole_controller_bytes_total", "Frame bytes on the wire (in = y, out = u).", "session", "params", "direction")
+	mSteps    = reg.Counter("cartpole_controller_steps_total", "Completed y -> u steps.", "session", "params")
+	mErrors   = reg.Counter("cartpole_controller_errors_total", "read_miss/write_miss = deadline misses, dropped = bad y frames (per session); rejected = failed handshake, session = ended without BYE (session=\"\", totals).", "session", "params", "kind")
+	mSessions = reg.Counter("cartpole_controller_sessions_total", "Plant sessions that passed HELLO.", "params")
+	mActive   = reg.Gauge("cartpole_controller_sessions_active", "Plant sessions currently in the y -> u loop.", "params")
+)
+
+// 모든 세션이 같이 쓰는 외부곱 goroutine (main 에서 한 번)
+var extWorkers *com_utils.ExtWorkers
+
+func numWorkers() int {
+	if *workers > 0 {
+		return *workers
+	}
+	return runtime.NumCPU()
+}
+
+// 프로세스 전체 heap 할당 횟수 (다른 세션 것도 포함), s 는 부르는 goroutine 전용
+func heapAllocs(s []rtmetrics.Sample) uint64 {
+	rtmetrics.Read(s)
+	return s[0].Value.Uint64()
+}
+
+// 파라미터 세트 라벨 (logN)
+func paramsLabel(mf *com_utils.Manifest) string {
+	return fmt.Sprintf("N%d", mf.Params.LogN())
+}
+
 // 첫 다항식의 첫 계수를 16진수 문자열로 반환
 func ctFirstCoeffHex(ct *rlwe.Ciphertext) string {
 	if ct == nil || len(ct.Value) == 0 {
@@ -41,104 +119,368 @@ func ctFirstCoeffHex(ct *rlwe.Ciphertext) string {
 	return fmt.Sprintf("0x%X", p.Coeffs[0][0])
 }
 
-func main() {
-	// ======== Parameters (저장 당시와 동일) ========
-	params, _ := rlwe.NewParametersFromLiteral(rlwe.ParametersLiteral{
-		LogN: 12, LogQ: []int{56}, LogP: []int{51}, NTTFlag: true,
-	})
-	ringQ := params.RingQ()
-
-	// Controller dims & quant (저장 당시와 동일)
-	n, m, pDim := 4, 1, 2
-	s := 1 / 10.0
-	L := 1 / 10000.0
-	r := 1 / 1000.0
-	_ = s
-	_ = L
-	_ = r
-
-	// tau & monomials (EncPack/Unpack 셋업)
-	maxDim := math.Max(math.Max(float64(n), float64(m)), float64(pDim))
-	tau := int(math.Pow(2, math.Ceil(math.Log2(maxDim))))
-	logn := int(math.Log2(float64(tau)))
-	monomials := make([]ring.Poly, logn)
-	for i := 0; i < logn; i++ {
-		monomials[i] = ringQ.NewPoly()
-		idx := params.N() - params.N()/(1<<(i+1))
-		monomials[i].Coeffs[0][idx] = 1
-		ringQ.MForm(monomials[i], monomials[i])
-		ringQ.NTT(monomials[i], monomials[i])
-	}
+// 한 plant 연결 (세션) 의 누적 통계
+type sessionStats struct {
+	id     int
+	remote string
+	bundle string
+	params string // metrics 라벨 (HELLO 전이면 "")
+	start  time.Time
+	end    string // 끝난 이유
+	clean  bool   // BYE / 스텝 제한으로 끝남
+
+	iters                int
+	readMiss, writeMiss  int
+	dropped, resets      int
+	recvBytes, sentBytes int64
+
+	recv, unpack, computeU, send, update, loop time.Duration
+	updateWait                                 time.Duration // 다음 Unpack 이 업데이트를 기다린 시간 (-pipeline)
+	maxLoop                                    time.Duration
+	allocs                                     uint64 // 첫 스텝 뒤 스텝 루프 안의 heap 할당 (창 출력 제외, 프로세스 전체)
+}
+
+func (st *sessionStats) String() string {
+	k := float64(max(st.iters, 1))
+	return fmt.Sprintf("bundle %q, %d steps in %v (%s) | avg recv %.3f, unpack %.3f, computeU %.3f, send %.3f, update %.3f (waited %.3f), loop %.3f (max %.3f) ms"+
+		" | misses read %d / write %d, dropped %d, resets %d | y %.1f KB, u %.1f KB | allocs/step %.1f",
+		st.bundle, st.iters, time.Since(st.start).Round(time.Millisecond), st.end,
+		ms(st.recv)/k, ms(st.unpack)/k, ms(st.computeU)/k, ms(st.send)/k, ms(st.update)/k, ms(st.updateWait)/k, ms(st.loop)/k, ms(st.maxLoop),
+		st.readMiss, st.writeMiss, st.dropped, st.resets,
+		float64(st.recvBytes)/k/1024, float64(st.sentBytes)/k/1024,
+		float64(st.allocs)/float64(max(st.iters-1, 1)))
+}
 
-	// ======== Load artifacts ========
-	base := filepath.Join("..", "02_Offline_task", "enc_data", "rgsw_for_N12")
+// plant 가 HELLO 의 id 로 고르는 제어기 (bundle 하나 = plant 하나의 키/pack)
+type plantBundle struct {
+	id   string
+	mf   *com_utils.Manifest
+	ctrl *com_utils.EncController // 세션은 NewSession 으로 복사해서 사용
+}
 
-	recoveredX := new(rlwe.Ciphertext)
-	if err := com_utils.ReadRT(filepath.Join(base, "xCtPack.dat"), recoveredX); err != nil {
-		log.Fatalf("load xCtPack: %v", err)
-	}
-	ctF, err := com_utils.LoadRGSWPack(base, "ctF")
+// keygen 출력 <out>/controller 를 id = <out> 폴더 이름으로 로드 (spec 과 manifest 확인)
+func loadBundle(dir string, sp *spec.Spec) (*plantBundle, error) {
+	bundle, err := com_utils.OpenControllerBundle(dir)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	ctG, err := com_utils.LoadRGSWPack(base, "ctG")
-	if err != nil {
-		log.Fatal(err)
+	if sp == nil {
+		// 여러 bundle 모드: 각 bundle 에 같이 저장된 spec 사본 기준
+		if sp, err = spec.Load(filepath.Join(dir, spec.FileName)); err != nil {
+			return nil, fmt.Errorf("load spec: %w", err)
+		}
 	}
-	ctH, err := com_utils.LoadRGSWPack(base, "ctH")
-	if err != nil {
-		log.Fatal(err)
+	if err := bundle.CheckSpec(sp); err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+	if bundle.Manifest.UnsafeSeed {
+		log.Printf("%s (bundle %s)", com_utils.UnsafeSeedWarning, dir)
 	}
-	ctJ, err := com_utils.LoadRGSWPack(base, "ctJ")
+	ctrl, err := bundle.LoadController()
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("%s: %w", dir, err)
 	}
+	id := filepath.Base(filepath.Dir(filepath.Clean(dir)))
+	return &plantBundle{id: id, mf: bundle.Manifest, ctrl: ctrl}, nil
+}
 
-	rlk := new(rlwe.RelinearizationKey)
-	if err := com_utils.ReadRT(filepath.Join(base, "rlk.dat"), rlk); err != nil {
-		log.Fatal(err)
+// -bundles 면 root 아래 controller bundle 전부, 아니면 -spec/-dir 하나
+func loadBundles() (map[string]*plantBundle, error) {
+	var dirs []string
+	var sp *spec.Spec
+	if *bundlesDir != "" {
+		matches, err := filepath.Glob(filepath.Join(*bundlesDir, "*", com_utils.RoleController, com_utils.ManifestName))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			dirs = append(dirs, filepath.Dir(m))
+		}
+		if len(dirs) == 0 {
+			return nil, fmt.Errorf("no */%s/%s under %s", com_utils.RoleController, com_utils.ManifestName, *bundlesDir)
+		}
+	} else {
+		var err error
+		if sp, err = spec.Load(*specPath); err != nil {
+			return nil, fmt.Errorf("load spec: %w", err)
+		}
+		dirs = []string{*artifactDir}
+	}
+	bundles := map[string]*plantBundle{}
+	for _, dir := range dirs {
+		b, err := loadBundle(dir, sp)
+		if err != nil {
+			return nil, err
+		}
+		bundles[b.id] = b
+		// slot 상태면 스텝마다 y 만 unpack (ctFGslot 이 없는 예전 bundle 은 packed)
+		state := "packed state"
+		if b.ctrl.FGs != nil {
+			state = fmt.Sprintf("slot state, %d update terms", len(b.ctrl.FGs))
+		}
+		fmt.Printf("[Controller] bundle %q: %s (n=%d, p=%d, logN=%d, %s)\n", b.id, dir, b.mf.N, b.mf.P, b.mf.Params.LogN(), state)
 	}
-	gks, err := com_utils.LoadGaloisKeys(base)
+	return bundles, nil
+}
+
+func main() {
+	// ======== Controller spec & artifacts ========
+	flag.Parse()
+
+	// 비밀키가 들어 있는 bundle 이거나, artifact 를 만든 spec 과 다르면 시작하지 않음
+	bundles, err := loadBundles()
 	if err != nil {
 		log.Fatal(err)
 	}
+	// 세션이 몇 개든 외부곱 goroutine 은 -workers 개 (세션끼리는 빈 worker 를 기다림)
+	extWorkers = com_utils.NewExtWorkers(numWorkers())
+	fmt.Printf("[Controller] %d external-product workers shared by all sessions\n", numWorkers())
+
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", reg)
+		go func() {
+			log.Printf("[Controller] metrics: %v", http.ListenAndServe(*metricsAddr, mux))
+		}()
+		fmt.Printf("[Controller] Metrics on http://%s/metrics\n", *metricsAddr)
+	}
 
-	evkRGSW := rlwe.NewMemEvaluationKeySet(rlk)
-	evkRLWE := rlwe.NewMemEvaluationKeySet(rlk, gks...)
-	evaluatorRGSW := rgsw.NewEvaluator(params, evkRGSW)
-	evaluatorRLWE := rlwe.NewEvaluator(params, evkRLWE)
+	// ======== server (plant 가 끊겨도 다음 연결을 받음, 세션마다 goroutine) ========
+	var ln io.Closer
+	var accept func() (net.Addr, io.Closer, func(st *sessionStats), error)
+	switch *transport {
+	case "tcp":
+		tl, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if tlsFiles.Enabled() {
+			conf, err := protocol.ServerTLS(tlsFiles)
+			if err != nil {
+				log.Fatal(err)
+			}
+			tl = tls.NewListener(tl, conf)
+			fmt.Println("[Controller] Listening on", addr, "(mutual TLS) ...")
+		} else {
+			fmt.Println("[Controller] Listening on", addr, "...")
+		}
+		ln = tl
+		accept = func() (net.Addr, io.Closer, func(st *sessionStats), error) {
+			conn, err := tl.Accept()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			return conn.RemoteAddr(), conn, func(st *sessionStats) {
+				defer conn.Close()
+				serveTCP(conn, bundles, st)
+			}, nil
+		}
+	case "udp":
+		if tlsFiles.Enabled() {
+			log.Fatal("-tls-*: mutual TLS needs -transport tcp (DTLS is not supported)")
+		}
+		ul, err := protocol.ListenUDP(addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("[Controller] Listening on", addr, "(udp) ...")
+		ln = ul
+		accept = func() (net.Addr, io.Closer, func(st *sessionStats), error) {
+			uc, err := ul.Accept()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			return uc.RemoteAddr(), uc, func(st *sessionStats) {
+				defer uc.Close()
+				uc.ReadTimeout = *readTimeout
+				uc.IdleTimeout = *udpIdle
+				serve(uc, bundles, st)
+				fmt.Printf("[Session %d] udp y %s\n", st.id, uc.Stats())
+			}, nil
+		}
+	default:
+		log.Fatalf("-transport: want tcp or udp, got %q", *transport)
+	}
 
-	zeroCt := rlwe.NewCiphertext(params, 1)
+	// 진행 중인 세션의 연결 (Ctrl+C 때 닫음)
+	var mu sync.Mutex
+	conns := map[int]io.Closer{}
+	stopping := false
+
+	// Ctrl+C → listener 와 세션 연결을 닫음 (세션은 read 에러로 끝나고 통계를 출력)
+	// 첫 신호 뒤 signal.Stop 이라 두 번째 Ctrl+C 는 기본 동작 (프로세스 바로 종료)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	go func() {
+		<-stop
+		signal.Stop(stop)
+		fmt.Println("\n[Controller] Interrupted, closing listener and sessions (Ctrl+C again to kill)")
+		ln.Close()
+		mu.Lock()
+		stopping = true
+		for _, c := range conns {
+			c.Close()
+		}
+		mu.Unlock()
+	}()
 
-	// ======== TCP server ========
-	ln, err := net.Listen("tcp", addr)
-	if err != nil {
-		log.Fatal(err)
+	var wg sync.WaitGroup
+	sessions := 0
+	for {
+		remote, conn, run, err := accept()
+		if errors.Is(err, net.ErrClosed) {
+			break
+		}
+		if err != nil {
+			log.Printf("[Controller] accept: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		sessions++
+		st := &sessionStats{id: sessions, remote: remote.String(), start: time.Now()}
+		mu.Lock()
+		if stopping {
+			mu.Unlock()
+			conn.Close()
+			continue
+		}
+		conns[st.id] = conn
+		mu.Unlock()
+		fmt.Printf("[Session %d] start: %s\n", st.id, st.remote)
+
+		// evaluator, 상태는 세션마다 따로, 외부곱 goroutine (-workers) 만 나눠 씀 → 세션이 많으면 스텝이 느려질 뿐 서로 막지 않음
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				mu.Lock()
+				delete(conns, st.id)
+				mu.Unlock()
+			}()
+			runSession(st, run)
+			if !st.clean {
+				kind := "session"
+				if st.params == "" {
+					kind = "rejected"
+				}
+				// 상대가 고를 수 있는 값 (bundle id, 주소) 이나 세션 번호는 라벨로 쓰지 않음
+				mErrors.With("", st.params, kind).Inc()
+			}
+			fmt.Printf("[Session %d] end: %s\n", st.id, st)
+		}()
 	}
-	defer ln.Close()
-	fmt.Println("[Controller] Listening on", addr, "...")
+	wg.Wait()
+	extWorkers.Close()
 
-	conn, err := ln.Accept()
-	if err != nil {
-		log.Fatal(err)
+	fmt.Printf("[Controller] Done. (%d sessions)\n", sessions)
+}
+
+// 세션 하나의 panic (깨진 입력 등) 은 그 세션만 끝냄 (서버와 다른 세션은 계속)
+func runSession(st *sessionStats, run func(st *sessionStats)) {
+	defer func() {
+		if r := recover(); r != nil {
+			st.end = fmt.Sprintf("panic: %v", r)
+			log.Printf("[Session %d] panic: %v\n%s", st.id, r, debug.Stack())
+		}
+	}()
+	run(st)
+}
+
+// TCP (TLS) 세션
+func serveTCP(conn net.Conn, bundles map[string]*plantBundle, st *sessionStats) {
+	// TLS: plant 인증서를 확인하기 전에는 프레임을 하나도 읽지 않음
+	if tc, ok := conn.(*tls.Conn); ok {
+		cn, err := protocol.Handshake(tc, handshakeTimeout)
+		if err != nil {
+			st.end = fmt.Sprintf("rejected: %v", err)
+			return
+		}
+		fmt.Printf("[Session %d] TLS peer %q\n", st.id, cn)
 	}
-	defer conn.Close()
-	rbuf := bufio.NewReader(conn)
-	wbuf := bufio.NewWriter(conn)
 
-	// ======== 누적치 (창 누적: 매 100회마다 출력) ========
-	var winRecv, winUnpack, winComputeU, winSend, winUpdate, winIter time.Duration
+	pc := protocol.NewConn(conn)
+	pc.ReadTimeout = *readTimeout
+	pc.WriteTimeout = *writeTimeout
+	serve(pc, bundles, st)
+}
+
+// 한 plant 세션: HELLO 확인 후 y → u 루프, 끝난 이유는 st.end
+func serve(pc protocol.Transport, bundles map[string]*plantBundle, st *sessionStats) {
+	// ======== HELLO (같은 spec 으로 만든 bundle 인지 확인) ========
+	hello, _, err := pc.Recv()
+	if err != nil {
+		st.end = fmt.Sprintf("read HELLO: %v", err)
+		return
+	}
+	if hello.Type != protocol.TypeHello {
+		pc.SendError(hello.Seq, fmt.Errorf("want HELLO, got %s", hello.Type))
+		st.end = fmt.Sprintf("want HELLO, got %s", hello.Type)
+		return
+	}
+	peer, err := hello.Hello()
+	if err != nil {
+		pc.SendError(0, err)
+		st.end = err.Error()
+		return
+	}
+	// id 로 bundle 선택 (id 가 없으면 bundle 이 하나일 때만 허용)
+	pb, ok := bundles[peer.BundleID]
+	if !ok && peer.BundleID == "" && len(bundles) == 1 {
+		for _, b := range bundles {
+			pb, ok = b, true
+		}
+	}
+	if !ok {
+		err := fmt.Errorf("unknown bundle id %q", peer.BundleID)
+		pc.SendError(0, err)
+		st.end = err.Error()
+		return
+	}
+	st.bundle = pb.id
+	mf := pb.mf
+	if peer.SpecSHA256 != mf.SpecSHA256 {
+		err := fmt.Errorf("spec mismatch: %s bundle built from %.12s..., controller %q from %.12s...", peer.Role, peer.SpecSHA256, pb.id, mf.SpecSHA256)
+		pc.SendError(0, err)
+		st.end = err.Error()
+		return
+	}
+	// 이후 y 프레임은 이 bundle 파라미터의 암호문 크기까지만 받음
+	yLimit, _ := com_utils.FrameLimits(mf.Params)
+	pc.SetMaxPayload(protocol.TypeY, yLimit)
+	reply := protocol.Hello{Role: com_utils.RoleController, SpecSHA256: mf.SpecSHA256, BundleID: pb.id}
+	if _, err := pc.SendHello(reply); err != nil {
+		st.end = fmt.Sprintf("send HELLO: %v", err)
+		return
+	}
+	fmt.Printf("[Session %d] HELLO from %s, bundle %q (state = initial xCtPack)\n", st.id, peer.Role, pb.id)
+
+	// 이 세션 전용 evaluator / 상태 (외부곱 goroutine 은 모든 세션이 공유)
+	ctrl := pb.ctrl.NewSession()
+	ctrl.ShareWorkers(extWorkers)
+	defer ctrl.Close()
+
+	// metrics series (세션, 파라미터 라벨은 세션 동안 고정)
+	st.params = paramsLabel(mf)
+	sid := strconv.Itoa(st.id)
+	defer reg.Delete("session", sid) // 다른 defer (finishUpdate 의 Observe) 뒤에
+	mSessions.With(st.params).Inc()
+	active := mActive.With(st.params)
+	active.Add(1)
+	defer active.Add(-1)
+	mRecv, mUnpack, mComputeU := mPhase.With(sid, st.params, "recv"), mPhase.With(sid, st.params, "unpack"), mPhase.With(sid, st.params, "compute_u")
+	mSend, mUpdate, mLoop := mPhase.With(sid, st.params, "send"), mPhase.With(sid, st.params, "update"), mPhase.With(sid, st.params, "loop")
+	mSendGap := mGap.With(sid, st.params)
+	mIn, mOut := mBytes.With(sid, st.params, "in"), mBytes.With(sid, st.params, "out")
+	mStep := mSteps.With(sid, st.params)
+	mReadMiss, mWriteMiss, mDropped := mErrors.With(sid, st.params, "read_miss"), mErrors.With(sid, st.params, "write_miss"), mErrors.With(sid, st.params, "dropped")
+	mWait := mPhase.With(sid, st.params, "update_wait")
+	var lastSent time.Time // 직전 u 송신 끝 (send → 다음 y 수신 간격)
+
+	// ======== 누적치 (창 누적: printEvery 회마다 출력) ========
+	var winUnpack, winComputeU, winSend, winUpdate, winIter time.Duration
 	var winRecvBytes, winSentBytes int64
-	var winSendToNextRecv time.Duration // 이전 루프 send 완료 → 다음 루프 y 수신 완료까지
-	var winCommSamples int              // 통신 표본 수
 	winCount := 0
 
-	itersDone := 0
-
-	// 이전 루프의 send 완료 시각
-	var lastSendDone time.Time
-	var haveLastSend bool
-
 	// 마지막 y/u 암호문 보관(샘플 계수 프린트용)
 	var lastYct *rlwe.Ciphertext
 	var lastUct *rlwe.Ciphertext
@@ -148,7 +490,6 @@ func main() {
 		if winCount == 0 {
 			return
 		}
-		// avgRecv := ms(winRecv) / float64(winCount) // 보내고 받을때까지 걸리는 시간
 		avgUnpack := ms(winUnpack) / float64(winCount)
 		avgComputeU := ms(winComputeU) / float64(winCount)
 		avgSend := ms(winSend) / float64(winCount)
@@ -161,116 +502,203 @@ func main() {
 			avgKB = float64(winRecvBytes) / float64(winCount) / 1024.0
 		}
 		fmt.Printf(
-			"\n[Controller]\n<Time and data size> (%d iterations)\n"+
+			"\n[Controller] session %d (%s)\n<Time and data size> (%d iterations)\n"+
 				"  Evaluate time : %7.3f ms\n"+
 				"  Loop time     : %7.3f ms\n"+
 				"  Cipher size   : %7.1f KB\n"+
+				"  Deadline miss : read %d, write %d (session)\n"+
 				" <I/O & State>\n"+
 				"  yCt           : %s\n"+
 				"  uCt           : %s\n"+
 				"  stateCt       : %s\n",
+			st.id, st.bundle,
 			winCount,
 			avgPhase,
 			avgTotal,
 			avgKB,
+			st.readMiss, st.writeMiss,
 			ctFirstCoeffHex(lastYct),
 			ctFirstCoeffHex(lastUct),
 			ctFirstCoeffHex(lastState),
 		)
-		// if winCommSamples > 0 {
-		// 	avgComm := ms(winSendToNextRecv) / float64(winCommSamples)
-		// 	fmt.Printf("[Controller][COMM last %d iteration]\n send->nextRecv = %.3f ms\n", winCommSamples, avgComm)
-		// }
-
-		// fmt.Printf("ctRGSW = %s\n", ctF[0].Value[0].Value[0][0]) //
-		// fmt.Printf("[Controller] [part of Encrypted Controller]\n F=%s | G=%s | H= %s | J= %s\n",
-		// 	ctF.Value(0), ctFirstCoeffHex(ctF[0].Value[0]), ctFirstCoeffHex(ctH), ctFirstCoeffHex(ctJ))
 	}
 
 	resetWindow := func() {
-		winRecv, winUnpack, winComputeU, winSend, winUpdate, winIter = 0, 0, 0, 0, 0, 0
+		winUnpack, winComputeU, winSend, winUpdate, winIter = 0, 0, 0, 0, 0
 		winRecvBytes, winSentBytes = 0, 0
-		winSendToNextRecv = 0
-		winCommSamples = 0
 		winCount = 0
 	}
+	defer printWindow() // 남은 창이 있으면 마지막으로 한 번 더 출력
+
+	// 진행 중인 상태 업데이트 (-pipeline): 다음 Unpack / Reset 전과 세션 끝에 기다림 → 다음 y 는 항상 새 상태를 봄
+	var pending <-chan time.Duration
+	finishUpdate := func() time.Duration {
+		if pending == nil {
+			return 0
+		}
+		t := time.Now()
+		dUpdate := <-pending
+		pending = nil
+		winUpdate += dUpdate
+		st.update += dUpdate
+		mUpdate.Observe(dUpdate.Seconds())
+		lastState = ctrl.State()
+		return time.Since(t)
+	}
+	defer finishUpdate() // printWindow 보다 먼저
+
+	// 스텝마다 재사용 (y 는 seed + b 로 받아 yCtPack 에 복원, u 는 ctrl 의 버퍼를 그대로 보냄)
+	ySeeded := new(com_utils.SeededCiphertext)
+	yCtPack := rlwe.NewCiphertext(ctrl.Params, 1)
+	uWire := new(com_utils.CtWire)
+	allocSample := []rtmetrics.Sample{{Name: "/gc/heap/allocs:objects"}}
 
 	// 메인 루프
 	for {
 		iterStart := time.Now()
+		allocStart := heapAllocs(allocSample)
 
-		// 1) receive y (ReadFrom 1회)
+		// 1) receive y (프레임 1개, seed 압축 → a 복원)
 		t := time.Now()
-		yCtPack := new(rlwe.Ciphertext)
-		nRecv, err := yCtPack.ReadFrom(rbuf)
+		frame, nRecv, err := pc.Recv()
+		if protocol.IsTimeout(err) {
+			// 읽던 프레임은 protocol.Conn 에 남아 있으므로 그대로 다시 대기
+			st.readMiss++
+			mReadMiss.Inc()
+			log.Printf("[Session %d] no y within %v (read misses %d)", st.id, *readTimeout, st.readMiss)
+			continue
+		}
+		if errors.Is(err, protocol.ErrChecksum) {
+			// 깨진 프레임은 버리고 plant 에 알림 (plant 는 그 스텝의 u 를 못 받음)
+			st.dropped++
+			mDropped.Inc()
+			log.Printf("[Session %d] %v (dropped)", st.id, err)
+			pc.SendError(frame.Seq, err)
+			continue
+		}
 		if err != nil {
-			log.Printf("[Controller] Read yCtPack err at iter %d: %v (stop)", itersDone, err)
-			break
+			st.end = fmt.Sprintf("read: %v", err)
+			return
 		}
-		dRecv := time.Since(t)
-		winRecvBytes += nRecv
-
-		// 직전 send 완료 → 이번 수신 완료까지
-		if haveLastSend {
-			winSendToNextRecv += time.Since(lastSendDone)
-			winCommSamples++
+		switch frame.Type {
+		case protocol.TypeY:
+		case protocol.TypeReset:
+			finishUpdate()
+			ctrl.Reset()
+			st.resets++
+			fmt.Printf("[Session %d] RESET (seq %d): state back to initial xCtPack\n", st.id, frame.Seq)
+			if _, err := pc.SendBytes(protocol.TypeReset, frame.Seq, nil); err != nil {
+				st.end = fmt.Sprintf("write RESET: %v", err)
+				return
+			}
+			continue
+		case protocol.TypeHello:
+			// HELLO 응답을 잃은 plant 의 재전송 (UDP) → 같은 응답
+			pc.SendHello(reply)
+			continue
+		case protocol.TypeBye:
+			st.end, st.clean = "BYE from plant", true
+			return
+		case protocol.TypeError:
+			st.end = frame.Err().Error()
+			return
+		default:
+			pc.SendError(frame.Seq, fmt.Errorf("unexpected %s frame", frame.Type))
+			continue
+		}
+		if !lastSent.IsZero() {
+			mSendGap.Observe(time.Since(lastSent).Seconds())
+		}
+		// 파라미터가 다른 y (N, level) 는 Unpack 전에 버림 (그대로 쓰면 evaluator 가 panic)
+		err = frame.Decode(ySeeded)
+		if err == nil {
+			err = ySeeded.Check(ctrl.Params, yCtPack.Level())
 		}
+		if err == nil {
+			err = ySeeded.ExpandInto(ctrl.Params, yCtPack)
+		}
+		if err != nil {
+			st.dropped++
+			mDropped.Inc()
+			log.Printf("[Session %d] %v (dropped)", st.id, err)
+			pc.SendError(frame.Seq, err)
+			continue
+		}
+		dRecv := time.Since(t)
 
-		// 2) unpack x,y
+		// 2) unpack y (+ packed 상태면 x, 직전 스텝의 업데이트가 아직이면 끝날 때까지 기다림)
+		dWait := finishUpdate()
 		t = time.Now()
-		xCt := RLWE.UnpackCt(recoveredX, n, tau, evaluatorRLWE, ringQ, monomials, params)
-		yCt := RLWE.UnpackCt(yCtPack, pDim, tau, evaluatorRLWE, ringQ, monomials, params)
+		xCt, yCt := ctrl.Unpack(yCtPack)
 		dUnpack := time.Since(t)
 
 		// 3) compute u = Hx + Jy
 		t = time.Now()
-		uCtPack := RGSW.MultPack(xCt, ctH, evaluatorRGSW, ringQ, params)
-		JyCt := RGSW.MultPack(yCt, ctJ, evaluatorRGSW, ringQ, params)
-		uCtPack = RLWE.Add(uCtPack, JyCt, zeroCt, params)
+		uCtPack := ctrl.Output(xCt, yCt)
 		dComputeU := time.Since(t)
 
-		// 4) send u (WriteTo 1회)
+		// 4) send u (y 와 같은 seq 로 프레임 1개)
 		t = time.Now()
-		nSent, err := uCtPack.WriteTo(wbuf)
-		if err != nil {
-			log.Printf("[Controller] Write uCtPack err at iter %d: %v (stop)", itersDone, err)
-			break
+		uWire.Ct = uCtPack
+		nSent, err := pc.Send(protocol.TypeU, frame.Seq, uWire)
+		if protocol.IsTimeout(err) {
+			// plant 는 이 seq 의 u 를 못 받고 대체 u 를 씀, 상태 업데이트는 y 를 받았으므로 그대로 진행
+			st.writeMiss++
+			mWriteMiss.Inc()
+			log.Printf("[Session %d] send u (seq %d) missed %v deadline (write misses %d)", st.id, frame.Seq, *writeTimeout, st.writeMiss)
+			err = nil
 		}
-		if err := wbuf.Flush(); err != nil {
-			log.Printf("[Controller] Flush err at iter %d: %v (stop)", itersDone, err)
-			break
+		if err != nil {
+			st.end = fmt.Sprintf("write u: %v", err)
+			return
 		}
 		dSend := time.Since(t)
-		winSentBytes += nSent
-
-		// 다음 루프 통신시간 계산용
-		lastSendDone = time.Now()
-		haveLastSend = true
+		lastSent = time.Now()
 
-		// 5) update x = F*x + G*y
-		t = time.Now()
-		FxCt := RGSW.MultPack(xCt, ctF, evaluatorRGSW, ringQ, params)
-		GyCt := RGSW.MultPack(yCt, ctG, evaluatorRGSW, ringQ, params)
-		recoveredX = RLWE.Add(FxCt, GyCt, zeroCt, params)
-		dUpdate := time.Since(t)
+		// 5) update x = F*x + G*y (pipeline 이면 goroutine 에서, 시간은 finishUpdate 에서 누적)
+		pending = ctrl.UpdateAsync(xCt, yCt)
+		if !*pipeline {
+			finishUpdate()
+		}
 
 		dIter := time.Since(iterStart)
 
-		// 창 누적
-		winRecv += dRecv
+		// 창 / 세션 누적
 		winUnpack += dUnpack
 		winComputeU += dComputeU
 		winSend += dSend
-		winUpdate += dUpdate
 		winIter += dIter
+		winRecvBytes += int64(nRecv)
+		winSentBytes += int64(nSent)
 		winCount++
 
+		mRecv.Observe(dRecv.Seconds())
+		mUnpack.Observe(dUnpack.Seconds())
+		mComputeU.Observe(dComputeU.Seconds())
+		mSend.Observe(dSend.Seconds())
+		mWait.Observe(dWait.Seconds())
+		mLoop.Observe(dIter.Seconds())
+		mIn.Add(float64(nRecv))
+		mOut.Add(float64(nSent))
+		mStep.Inc()
+
+		st.iters++
+		st.recv += dRecv
+		st.unpack += dUnpack
+		st.computeU += dComputeU
+		st.send += dSend
+		st.updateWait += dWait
+		st.loop += dIter
+		st.maxLoop = max(st.maxLoop, dIter)
+		st.recvBytes += int64(nRecv)
+		st.sentBytes += int64(nSent)
+		if st.iters > 1 {
+			st.allocs += heapAllocs(allocSample) - allocStart
+		}
+
 		// 샘플 계수용
 		lastYct = yCtPack
 		lastUct = uCtPack
-		lastState = recoveredX
-
-		itersDone++
 
 		// 적당한 루프마다 프린트 찍기
 		if winCount >= printEvery {
@@ -284,12 +712,10 @@ func main() {
 		}
 
 		// (선택) 고정 횟수 종료
-		if numIters > 0 && itersDone >= numIters {
-			// 남은 창이 있으면 마지막으로 한 번 더 출력
-			printWindow()
-			break
+		if numIters > 0 && st.iters >= numIters {
+			pc.SendBytes(protocol.TypeBye, frame.Seq, nil) // plant 가 먼저 끊었으면 실패해도 무시
+			st.end, st.clean = fmt.Sprintf("reached %d steps", numIters), true
+			return
 		}
 	}
-
-	fmt.Println("[Controller] Done.)")
 }
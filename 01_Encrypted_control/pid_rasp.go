@@ -8,6 +8,8 @@ import (
 	"strings"
 	"time"
 
+	"Encrypted_Cartpole/03_Utils/pid"
+
 	"go.bug.st/serial"
 )
 
@@ -27,12 +29,10 @@ const (
 
 
 
-// 제어기 행렬
-var C = []float64{Ki, -Kd, Li, -Ld}
-var D = []float64{Kp + Ki + Kd, Lp + Li + Ld}
+// 제어기 (암호 제어기와 같은 pid realization, 4x1 누산/보유 상태)
+var ctrl *pid.Controller
 
-// 상태, 출력, 입력
-var state = []float64{0, 0, 0, 0} // 4x1 누산/보유 상태
+// 출력, 입력
 var y = []float64{0, 0}
 var u = 0.0
 
@@ -49,6 +49,16 @@ const (
 )
 
 func main() {
+	ss, err := pid.Build(1,
+		pid.Channel{Kp: Kp, Ki: Ki, Kd: Kd},
+		pid.Channel{Kp: Lp, Ki: Li, Kd: Ld},
+	)
+	if err != nil {
+		fmt.Println("pid build failed:", err)
+		return
+	}
+	ctrl, _ = pid.NewController(ss, make([]float64, len(ss.F)))
+
 	mode := &serial.Mode{BaudRate: BAUD}
 	port, err := serial.Open(SERIAL_DEV, mode)
 	if err != nil {
@@ -85,22 +95,15 @@ func main() {
 		}
 		tRecv := time.Now()
 
-		// 2) u 계산 (u = Cx + Dy)
+		// 2) u 계산 (u = Hx + Jy) + 상태 업데이트
 		y[0], y[1] = y0, y1
-		u = C[0]*state[0] + C[1]*state[1] + C[2]*state[2] + C[3]*state[3] +
-			D[0]*y[0] + D[1]*y[1]
+		u = ctrl.Step(y)[0]
 
 		// 간단 각도 보호
 		if y[0] > angleLimit || y[0] < -angleLimit {
 			u = 0
 		}
 
-		// 상태 업데이트
-		state[0] += y[0]
-		state[1] = y[0]
-		state[2] += y[1]
-		state[3] = y[1]
-
 		// 3) 15ms 대기 후 회신
 		time.Sleep(SLEEP_MS * time.Millisecond)
 		if _, err := port.Write([]byte(fmt.Sprintf("%.6f\n", u))); err != nil {
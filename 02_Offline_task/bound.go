@@ -0,0 +1,89 @@
+package main
+
+import (
+	"Encrypted_Cartpole/03_Utils/analysis"
+	"Encrypted_Cartpole/03_Utils/quant"
+	"Encrypted_Cartpole/03_Utils/spec"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// 폐루프 오차 상한 (README 의 ||u|| < 0.1901) 확인
+//
+//	go run bound.go -spec ../config/cartpole_N12.json -plant cartpole_plant.json
+//	go run bound.go -input 1 -noise 0              (plant 의 두번째 입력, 양자화만)
+//	go run bound.go -fourtank                      (offline_rlwe.go 의 MATLAB four-tank 설계, 부호 규약 확인용)
+//
+// -plant 가 없으면 offline_rlwe.go 의 four-tank A, B, C (카트폴 모델이 아니라 PID 결과는 참고용)
+// 카트폴은 아두이노가 오차 (target - y) 를 보내므로 기본값 -error-output 으로 제어기 입력을 -C x_p 로 둠
+// PID 는 입력이 1개라 -input 으로 B 의 열을 고름. margin 을 넘거나 폐루프가 불안정하면 exit code 1
+var (
+	specPath   = flag.String("spec", filepath.Join("..", "config", "cartpole_N12.json"), "controller spec file")
+	inputCol   = flag.Int("input", 0, "plant input (column of B) driven by the controller output")
+	margin     = flag.Float64("margin", analysis.DefaultMargin, "allowed steady-state |Δu|")
+	noiseTrial = flag.Int("noise", 20, "encryptions used to measure noise (0 = quantization only)")
+	plantPath  = flag.String("plant", "", "plant model JSON {A, B, C, errorOutput} (empty = four-tank model of offline_rlwe.go)")
+	errOutput  = flag.Bool("error-output", true, "controller input is target - C x_p as sent by ardu.ino (four-tank model only; -plant files set errorOutput)")
+	fourTank   = flag.Bool("fourtank", false, "check the four-tank design of offline_rlwe.go (MATLAB, y = C x_p) instead of -spec")
+)
+
+func main() {
+	flag.Parse()
+
+	if *fourTank {
+		pb := analysis.FourTankProblem()
+		pb.Margin = *margin
+		report(pb)
+		return
+	}
+
+	sp, err := spec.Load(*specPath)
+	if err != nil {
+		log.Fatalf("load spec: %v", err)
+	}
+
+	var plant analysis.Plant
+	if *plantPath != "" {
+		if plant, err = analysis.LoadPlant(*plantPath); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		plant = analysis.OfflinePlant()
+		plant.ErrorOutput = *errOutput
+		log.Println("[NOTE] four-tank model of offline_rlwe.go is not the cartpole; pass -plant <model> to check the PID gains")
+	}
+	if _, m, _ := sp.Dims(); m != len(plant.B[0]) {
+		if plant, err = plant.Input(*inputCol); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	pb := analysis.DefaultProblem(sp, plant)
+	pb.Margin = *margin
+	if *noiseTrial > 0 {
+		params, err := sp.RLWEParams()
+		if err != nil {
+			log.Fatalf("params: %v", err)
+		}
+		pb.Noise = quant.MeasureNoise(params, sp.Tau(), *noiseTrial)
+		fmt.Printf("Noise σ (N=2^%d): fresh %.1f, slot %.1f, external product %.1f\n",
+			params.LogN(), pb.Noise.Fresh, pb.Noise.Slot, pb.Noise.Ext)
+	}
+	report(pb)
+}
+
+func report(pb analysis.Problem) {
+	rep, err := analysis.Analyze(pb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if rep.Within() {
+		fmt.Println("[OK]  ", rep)
+		return
+	}
+	fmt.Println("[FAIL]", rep)
+	os.Exit(1)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// plant ↔ controller 상호 인증 TLS 용 lab CA + 노드 인증서 발급
+//
+//	go run certs.go -out certs -controller-hosts 127.0.0.1,192.168.0.115 -plants cartpole
+//
+// <out>/ca.crt, ca.key        : lab CA (ca.key 는 이 PC 에만 두고 노드로 복사하지 않음, 있으면 재사용)
+// <out>/controller.crt, .key  : controller (ServerAuth, SAN = -controller-hosts)
+// <out>/<plant>.crt, .key     : plant 마다 (ClientAuth, CN = plant 이름)
+//
+// 노드에는 ca.crt + 자기 인증서/키만 복사 (controller / plant 의 -tls-ca, -tls-cert, -tls-key)
+var (
+	outDir     = flag.String("out", "certs", "output directory")
+	ctrlHosts  = flag.String("controller-hosts", "127.0.0.1,localhost", "comma separated IPs / host names plants dial the controller at")
+	plantNames = flag.String("plants", "cartpole", "comma separated plant names, one client certificate each")
+	days       = flag.Int("days", 365, "node certificate validity in days")
+	caDays     = flag.Int("ca-days", 3650, "CA validity in days (new CA only)")
+)
+
+func splitList(s string) []string {
+	out := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func serial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// PEM 저장 (키는 0600)
+func writePEM(path, typ string, der []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if err := pem.Encode(f, &pem.Block{Type: typ, Bytes: der}); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func writeKeyPair(name string, certDER []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := writePEM(filepath.Join(*outDir, name+".key"), "PRIVATE KEY", keyDER, 0o600); err != nil {
+		return err
+	}
+	return writePEM(filepath.Join(*outDir, name+".crt"), "CERTIFICATE", certDER, 0o644)
+}
+
+func readPEM(path, typ string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	blk, _ := pem.Decode(b)
+	if blk == nil || blk.Type != typ {
+		return nil, fmt.Errorf("%s: no %s PEM block", path, typ)
+	}
+	return blk.Bytes, nil
+}
+
+// 기존 CA 를 읽거나 없으면 새로 만듦
+func loadOrCreateCA() (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	crtPath, keyPath := filepath.Join(*outDir, "ca.crt"), filepath.Join(*outDir, "ca.key")
+	if _, err := os.Stat(crtPath); err == nil {
+		der, err := readPEM(crtPath, "CERTIFICATE")
+		if err != nil {
+			return nil, nil, err
+		}
+		ca, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, nil, err
+		}
+		der, err = readPEM(keyPath, "PRIVATE KEY")
+		if err != nil {
+			return nil, nil, err
+		}
+		k, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := k.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: want an ECDSA key", keyPath)
+		}
+		fmt.Println("[CERTS] reusing CA", crtPath, "(", ca.Subject.CommonName, ")")
+		return ca, key, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	sn, err := serial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          sn,
+		Subject:               pkix.Name{CommonName: "Encrypted_Cartpole lab CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(0, 0, *caDays),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeKeyPair("ca", der, key); err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Println("[CERTS] new CA", crtPath)
+	return ca, key, nil
+}
+
+// CA 로 노드 인증서 발급 (usage = ServerAuth 또는 ClientAuth 하나만)
+func issue(ca *x509.Certificate, caKey *ecdsa.PrivateKey, name string, usage x509.ExtKeyUsage, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	sn, err := serial()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: sn,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(0, 0, *days),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	if tmpl.NotAfter.After(ca.NotAfter) {
+		tmpl.NotAfter = ca.NotAfter
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	if err := writeKeyPair(name, der, key); err != nil {
+		return err
+	}
+	fmt.Printf("[CERTS] %s.crt / %s.key (until %s)\n", name, name, tmpl.NotAfter.Format("2006-01-02"))
+	return nil
+}
+
+func main() {
+	flag.Parse()
+
+	hosts := splitList(*ctrlHosts)
+	plants := splitList(*plantNames)
+	if len(hosts) == 0 {
+		log.Fatal("-controller-hosts: need at least one IP or host name")
+	}
+	for _, p := range plants {
+		if p == "ca" || p == "controller" || strings.ContainsAny(p, `/\`) {
+			log.Fatalf("-plants: bad plant name %q", p)
+		}
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	ca, caKey, err := loadOrCreateCA()
+	if err != nil {
+		log.Fatalf("CA: %v", err)
+	}
+	if err := issue(ca, caKey, "controller", x509.ExtKeyUsageServerAuth, hosts); err != nil {
+		log.Fatalf("controller: %v", err)
+	}
+	for _, p := range plants {
+		if err := issue(ca, caKey, p, x509.ExtKeyUsageClientAuth, nil); err != nil {
+			log.Fatalf("plant %s: %v", p, err)
+		}
+	}
+	fmt.Println("[CERTS] copy ca.crt + controller.crt/.key to the controller PC, ca.crt + <plant>.crt/.key to each plant (never ca.key)")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"Encrypted_Cartpole/03_Utils/arx"
+	"Encrypted_Cartpole/03_Utils/spec"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// conversion.m 대체: LTI 제어기 (A, B, C, D, x0) → ARX (Hu, Hy, D, Uini, Yini)
+//
+//	go run convert.go -in controller.json -o arx.json
+//	go run convert.go -spec ../config/cartpole_N12.json   (PID spec 의 F, G, H, J)
+//
+// 입력 JSON: {"A": [[...]], "B": [[...]], "C": [[...]], "D": [[...]], "x0": [...]} (D, x0 생략 시 0)
+// 출력에는 offline_rlwe.go 가 쓰는 vecHu', vecHy' 형태도 같이 저장
+var (
+	inPath   = flag.String("in", "", "LTI controller JSON")
+	specPath = flag.String("spec", "", "use the PID realization of this spec instead of -in")
+	outPath  = flag.String("o", "", "output JSON (default stdout)")
+	steps    = flag.Int("check", 200, "random-input steps used to compare ARX against the LTI (0 = skip)")
+	maxDev   = flag.Float64("max-dev", 1e-6, "fail without writing output if max |u_arx - u_lti| over -check steps exceeds this")
+)
+
+type ltiFile struct {
+	arx.LTI
+	X0 []float64 `json:"x0,omitempty"`
+}
+
+type arxFile struct {
+	*arx.ARX
+	VecHu [][]float64 `json:"vecHu"`
+	VecHy [][]float64 `json:"vecHy"`
+}
+
+func main() {
+	flag.Parse()
+
+	var in ltiFile
+	switch {
+	case *specPath != "":
+		sp, err := spec.Load(*specPath)
+		if err != nil {
+			log.Fatalf("load spec: %v", err)
+		}
+		F, G, H, _, J := sp.Matrices()
+		in = ltiFile{LTI: arx.LTI{A: F, B: G, C: H, D: J}, X0: sp.XIni}
+	case *inPath != "":
+		b, err := os.ReadFile(*inPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(b, &in); err != nil {
+			log.Fatalf("parse %s: %v", *inPath, err)
+		}
+	default:
+		log.Fatal("need -in or -spec")
+	}
+
+	a, err := arx.Convert(&in.LTI, in.X0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *steps > 0 {
+		dev := a.Verify(&in.LTI, in.X0, *steps)
+		fmt.Fprintf(os.Stderr, "[ARX] n=%d, max |u_arx - u_lti| over %d random steps: %.3g\n", a.N, *steps, dev)
+		if !(dev <= *maxDev) { // NaN 도 실패
+			log.Fatalf("ARX realization deviates from the LTI controller by %.3g (> -max-dev %.3g), not written", dev, *maxDev)
+		}
+	}
+
+	out := arxFile{ARX: a}
+	out.VecHu, out.VecHy = a.Vectorized()
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *outPath == "" {
+		os.Stdout.Write(append(b, '\n'))
+		return
+	}
+	if err := os.WriteFile(*outPath, append(b, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintln(os.Stderr, "[ARX] wrote", *outPath)
+}
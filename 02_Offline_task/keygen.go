@@ -0,0 +1,443 @@
+package main
+
+import (
+	com_utils "Encrypted_Cartpole/03_Utils"
+	"Encrypted_Cartpole/03_Utils/pid"
+	"Encrypted_Cartpole/03_Utils/quant"
+	"Encrypted_Cartpole/03_Utils/security"
+	"Encrypted_Cartpole/03_Utils/spec"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	utils "github.com/CDSL-EncryptedControl/CDSL/utils"
+	RGSW "github.com/CDSL-EncryptedControl/CDSL/utils/core/RGSW"
+	RLWE "github.com/CDSL-EncryptedControl/CDSL/utils/core/RLWE"
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// offline keygen: 제어기 spec + RLWE 파라미터 → 암호화된 제어기 artifact 생성
+//
+//	go run keygen.go -spec ../config/cartpole_N12.json
+//	go run keygen.go -spec ../config/cartpole_N10.json -out enc_data/rgsw_for_N10
+//	go run keygen.go -logN 11 -logQ 28 -logP 28      (spec 의 파라미터 덮어쓰기)
+//	go run keygen.go -auto-scales                     (r, s, L 을 quant.Select 로 선택)
+//
+// 128-bit 보안(HE standard 표)을 만족하지 않는 파라미터는 -allow-insecure 없이는 거부
+var (
+	specPath = flag.String("spec", filepath.Join("..", "config", "cartpole_N12.json"), "controller spec file")
+	logNFlag = flag.Int("logN", 0, "override spec logN (0 = use spec)")
+	logQFlag = flag.String("logQ", "", "override spec logQ, comma separated (empty = use spec)")
+	logPFlag = flag.String("logP", "", "override spec logP, comma separated (empty = use spec)")
+	outDir   = flag.String("out", "", "output directory (default enc_data/rgsw_for_N<logN>)")
+	force    = flag.Bool("force", false, "overwrite a bundle already in -out (its manifest.json, sealed sk and packs)")
+	iterFlag = flag.Int("iter", 500, "offline simulation iterations after saving, u*.csv go to -out (0 = skip)")
+	yFlag    = flag.String("y", "-2,2", "constant plant output used in the offline simulation")
+
+	minSecurity   = flag.Int("min-security", 128, "required security level in bits (HE standard tables)")
+	allowInsecure = flag.Bool("allow-insecure", false, "generate keys even if the parameters miss -min-security")
+	autoScales    = flag.Bool("auto-scales", false, "replace the spec scales with quant.Select defaults (see scales.go)")
+	unsafeSeed    = flag.String("unsafe-seed", "", "UNSAFE, tests only: derive every key/ciphertext sample from this seed (byte-identical output)")
+)
+
+func parseFloats(str string) ([]float64, error) {
+	out := []float64{}
+	for _, f := range strings.Split(str, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad float list %q: %w", str, err)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func main() {
+	flag.Parse()
+
+	// 결정적 모드: keygen / encryptor 의 sampler 에만 seed 를 넣음 (sk 봉인 salt / nonce 는 crypto/rand)
+	var (
+		seed *com_utils.UnsafeSeed
+		err  error
+	)
+	if *unsafeSeed != "" {
+		if seed, err = com_utils.NewUnsafeSeed(*unsafeSeed); err != nil {
+			log.Fatal(err)
+		}
+		log.Println(com_utils.UnsafeSeedWarning)
+	}
+
+	// ================= 0) Controller spec =================
+	sp, err := spec.Load(*specPath)
+	if err != nil {
+		log.Fatalf("load spec: %v", err)
+	}
+	// 커맨드라인 파라미터가 있으면 spec 을 덮어씀 (저장되는 spec.json 에도 반영)
+	if err := sp.Params.Override(*logNFlag, *logQFlag, *logPFlag); err != nil {
+		log.Fatal(err)
+	}
+	if err := sp.Validate(); err != nil {
+		log.Fatalf("invalid spec: %v", err)
+	}
+	fmt.Println("Controller spec:", sp.Name, "(", *specPath, ")")
+
+	base := *outDir
+	if base == "" {
+		base = filepath.Join("enc_data", fmt.Sprintf("rgsw_for_N%d", sp.Params.LogN))
+	}
+	// 기본 폴더는 logN 만으로 정해짐 → 다른 spec 이 같은 N 이면 배포된 bundle (봉인된 sk 포함) 을 덮어쓸 수 있음
+	for _, role := range []string{com_utils.RoleController, com_utils.RolePlant} {
+		mf := filepath.Join(base, role, com_utils.ManifestName)
+		if _, err := os.Stat(mf); err == nil && !*force {
+			log.Fatalf("%s already exists; pass -out <new dir> or -force to overwrite that bundle", mf)
+		}
+	}
+
+	// 보안 수준 확인 (HE standard 표), 부족하면 -allow-insecure 없이는 중단
+	secParams, err := sp.RLWEParams()
+	if err != nil {
+		log.Fatalf("params: %v", err)
+	}
+	if rep, err := security.Check(secParams, *minSecurity); err != nil {
+		if !*allowInsecure {
+			log.Fatalf("%v (pass -allow-insecure to generate anyway)", err)
+		}
+		log.Printf("[WARN] %v — continuing because of -allow-insecure", err)
+	} else {
+		fmt.Println("Security:", rep)
+	}
+
+	// 양자화 스케일 자동 선택 (저장되는 spec.json 에 반영 → controller/plant 는 그 spec 을 사용)
+	if *autoScales {
+		pb := quant.DefaultProblem(sp)
+		pb.Noise = quant.MeasureNoiseSeeded(secParams, sp.Tau(), 20, seed.Sub("noise"))
+		res, err := quant.Select(pb)
+		if err != nil {
+			if res.TotalErr > 0 {
+				fmt.Println("Closest scales:", res)
+			}
+			log.Fatalf("auto scales: %v (tune the bounds with scales.go -o and pass that spec)", err)
+		}
+		sp.Scales = res.Scales
+		fmt.Println("Auto scales:", res)
+		log.Printf("[NOTE] scales differ from %s; run controller/plant with -spec <out>/controller/spec.json", *specPath)
+	}
+
+	// plant bundle 의 비밀키를 잠글 passphrase (환경변수 CARTPOLE_SK_PASSPHRASE 또는 프롬프트)
+	passphrase, err := com_utils.Passphrase("Passphrase for the plant secret key: ", true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	yTest, err := parseFloats(*yFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, _, p := sp.Dims(); len(yTest) != p {
+		log.Fatalf("-y: want %d values, got %d", p, len(yTest))
+	}
+
+	// ================= 1) Encryption parameters =================
+	params, err := sp.RLWEParams()
+	if err != nil {
+		log.Fatalf("params: %v", err)
+	}
+	fmt.Println("Degree of polynomials:", params.N())
+	fmt.Println("Ciphertext modulus:", params.QBigInt())
+	fmt.Println("Special modulus:", params.PBigInt())
+	fmt.Println("Secret key distribution (Ternary):", params.Xs())
+	fmt.Println("Error distribution (Discrete Gaussian):", params.Xe())
+
+	// ================= Controller (PID-based) =================
+	F, G, H, R, J := sp.Matrices()
+
+	// Controller initial state
+	x_ini := append([]float64(nil), sp.XIni...)
+
+	// Dimensions
+	n, m, p := sp.Dims()
+
+	// ================= 2) Quantization parameters =================
+	s := sp.Scales.S
+	L := sp.Scales.L
+	r := sp.Scales.R
+	fmt.Printf("Scaling parameters 1/L: %v, 1/s: %v, 1/r: %v\n", 1/L, 1/s, 1/r)
+
+	// ================= 3) Rings / aux =================
+	levelQ := params.QCount() - 1
+	levelP := params.PCount() - 1
+	_ = levelQ
+	_ = levelP
+
+	ringQ := params.RingQ()
+
+	// tau, monomials (for RLWE pack/unpack)
+	// Galois elements (for rotations used in Unpack)
+	tau := sp.Tau()
+	monomials, galEls := com_utils.PackSetup(params, tau)
+
+	// ================= 4) KeyGen & encryptors/evaluators =================
+	kgen := seed.KeyGenerator(params)
+	sk := kgen.GenSecretKeyNew()
+	rlk := kgen.GenRelinearizationKeyNew(sk)
+	gks := kgen.GenGaloisKeysNew(galEls, sk)
+
+	encryptorRLWE := seed.Encryptor(params, sk)
+	// decryptorRLWE := rlwe.NewDecryptor(params, sk) // <- 사용 안 하므로 제거
+	encryptorRGSW := seed.RGSWEncryptor(params, sk)
+
+	// ================= 5) Encrypt controller matrices =================
+	GBar := utils.ScalMatMult(1/s, G)
+	HBar := utils.ScalMatMult(1/s, H)
+	RBar := utils.ScalMatMult(1/s, R)
+	JBar := utils.ScalMatMult(1/(s*s), J)
+
+	ctF := RGSW.EncPack(F, tau, encryptorRGSW, levelQ, levelP, ringQ, params)
+	ctG := RGSW.EncPack(GBar, tau, encryptorRGSW, levelQ, levelP, ringQ, params)
+	ctH := RGSW.EncPack(HBar, tau, encryptorRGSW, levelQ, levelP, ringQ, params)
+	ctR := RGSW.EncPack(RBar, tau, encryptorRGSW, levelQ, levelP, ringQ, params) // 사용 안 하지만 저장은 함
+	ctJ := RGSW.EncPack(JBar, tau, encryptorRGSW, levelQ, levelP, ringQ, params)
+	_ = ctR
+	// 상태 갱신용 [F | Ḡ] 원소별 RGSW (controller 가 상태를 slot 별로 유지, 매 스텝 x unpack 생략)
+	slotEntries, ctFGslot := com_utils.EncSlotPack(F, GBar, encryptorRGSW, levelQ, levelP, params)
+
+	// initial state x (packed RLWE)
+	xBar := utils.RoundVec(utils.ScalVecMult(1/(r*s), x_ini))
+	xCtPack := RLWE.EncPack(xBar, tau, 1/L, *encryptorRLWE, ringQ, params)
+
+	// Zero ct
+	zeroCt := rlwe.NewCiphertext(params, 1)
+
+	// ================= 6) SAVE all artifacts =================
+	// controller bundle: 평가용 (RGSW pack, xCtPack, rlk, Galois key) — 비밀키 없음
+	// plant bundle     : sk + 스케일 (spec/manifest)
+	ctrlDir := filepath.Join(base, com_utils.RoleController)
+	plantDir := filepath.Join(base, com_utils.RolePlant)
+	for _, dir := range []string{ctrlDir, plantDir} {
+		if err := com_utils.EnsureDir(dir); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctrlMf := com_utils.NewManifest(com_utils.RoleController, sp, params, galEls)
+	ctrlMf.UnsafeSeed = *unsafeSeed != ""
+	ctrlMf.SlotEntries = slotEntries
+	ctrlFiles := []string{}
+
+	// ciphertexts
+	if err := com_utils.WriteWT(filepath.Join(ctrlDir, "xCtPack.dat"), xCtPack); err != nil {
+		log.Fatalf("save xCtPack failed: %v", err)
+	}
+	ctrlFiles = append(ctrlFiles, "xCtPack.dat")
+	packs := []struct {
+		name string
+		pack []*rgsw.Ciphertext
+	}{
+		{"ctF", ctF}, {"ctG", ctG}, {"ctH", ctH}, {"ctR", ctR}, {"ctJ", ctJ}, {"ctFGslot", ctFGslot},
+	}
+	for _, pk := range packs {
+		if err := com_utils.SaveRGSWPack(ctrlDir, pk.name, pk.pack); err != nil {
+			log.Fatal(err)
+		}
+		ctrlMf.Packs[pk.name] = len(pk.pack)
+		ctrlFiles = append(ctrlFiles, com_utils.PackFiles(pk.name, len(pk.pack))...)
+	}
+
+	// evaluation keys
+	if err := com_utils.WriteWT(filepath.Join(ctrlDir, "rlk.dat"), rlk); err != nil {
+		log.Fatalf("save rlk failed: %v", err)
+	}
+	ctrlFiles = append(ctrlFiles, "rlk.dat")
+	for i, gk := range gks {
+		fn := com_utils.GaloisKeyFile(galEls[i])
+		if err := com_utils.WriteWT(filepath.Join(ctrlDir, fn), gk); err != nil {
+			log.Fatalf("save gk(%d) failed: %v", galEls[i], err)
+		}
+		ctrlFiles = append(ctrlFiles, fn)
+	}
+
+	// secret key → plant bundle 에만 (passphrase 로 암호화, 0600)
+	plantMf := com_utils.NewManifest(com_utils.RolePlant, sp, params, galEls)
+	plantMf.UnsafeSeed = *unsafeSeed != ""
+	if err := com_utils.WriteSealedKey(filepath.Join(plantDir, com_utils.SecretKeyFile), sk, passphrase); err != nil {
+		log.Fatalf("save sk failed: %v", err)
+	}
+	plantFiles := []string{com_utils.SecretKeyFile}
+
+	// 양쪽 bundle 에 spec 사본 + manifest (파라미터, 차원, 스케일, 파일별 SHA-256)
+	for _, bd := range []struct {
+		dir   string
+		mf    *com_utils.Manifest
+		files []string
+	}{
+		{ctrlDir, ctrlMf, ctrlFiles},
+		{plantDir, plantMf, plantFiles},
+	} {
+		if err := spec.Save(filepath.Join(bd.dir, spec.FileName), sp); err != nil {
+			log.Fatalf("save spec failed: %v", err)
+		}
+		if err := bd.mf.HashFiles(bd.dir, append(bd.files, spec.FileName)...); err != nil {
+			log.Fatal(err)
+		}
+		if err := com_utils.WriteManifest(bd.dir, bd.mf); err != nil {
+			log.Fatalf("save manifest failed: %v", err)
+		}
+	}
+	fmt.Println("[SAVE] controller bundle:", ctrlDir)
+	fmt.Println("[SAVE] plant bundle     :", plantDir)
+
+	if *iterFlag <= 0 {
+		return
+	}
+
+	// ================= 7) LOAD artifacts as recovered_* =================
+	bundle, err := com_utils.OpenControllerBundle(ctrlDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	plantBundle, err := com_utils.OpenPlantBundle(plantDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, b := range []*com_utils.Bundle{bundle, plantBundle} {
+		if err := b.CheckSpec(sp); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	recoveredX := new(rlwe.Ciphertext)
+	if err = bundle.ReadRT("xCtPack.dat", recoveredX); err != nil {
+		log.Fatalf("load xCtPack failed: %v", err)
+	}
+
+	var recoveredF, recoveredG, recoveredH, recoveredJ []*rgsw.Ciphertext
+
+	recoveredF, err = bundle.LoadRGSWPack("ctF")
+	if err != nil {
+		log.Fatalf("load ctF failed: %v", err)
+	}
+
+	recoveredG, err = bundle.LoadRGSWPack("ctG")
+	if err != nil {
+		log.Fatalf("load ctG failed: %v", err)
+	}
+
+	recoveredH, err = bundle.LoadRGSWPack("ctH")
+	if err != nil {
+		log.Fatalf("load ctH failed: %v", err)
+	}
+
+	// R은 사용 안 하므로 읽지 않아도 됨 (필요하면 아래처럼 버리기)
+	// _, _ = bundle.LoadRGSWPack("ctR")
+
+	recoveredJ, err = bundle.LoadRGSWPack("ctJ")
+	if err != nil {
+		log.Fatalf("load ctJ failed: %v", err)
+	}
+
+	recoveredRlk := new(rlwe.RelinearizationKey)
+	if err = bundle.ReadRT("rlk.dat", recoveredRlk); err != nil {
+		log.Fatalf("load rlk failed: %v", err)
+	}
+	recoveredGks, err := bundle.LoadGaloisKeys()
+	if err != nil {
+		log.Fatalf("load gk_* failed: %v", err)
+	}
+
+	recoveredSk, err := plantBundle.ReadSecretKey(passphrase)
+	if err != nil {
+		log.Fatalf("load sk failed: %v", err)
+	}
+
+	// ================= 8) Rebuild evaluators/cryptors from recovered keys =================
+	evkRGSW2 := rlwe.NewMemEvaluationKeySet(recoveredRlk)
+	evkRLWE2 := rlwe.NewMemEvaluationKeySet(recoveredRlk, recoveredGks...)
+	evaluatorRGSW2 := rgsw.NewEvaluator(params, evkRGSW2)
+	evaluatorRLWE2 := rlwe.NewEvaluator(params, evkRLWE2)
+	encryptorRLWE2 := seed.Sub("sim").Encryptor(params, recoveredSk)
+	decryptorRLWE2 := rlwe.NewDecryptor(params, recoveredSk)
+
+	// ================= 9) Simulation: baseline (unencrypted) =================
+	iter := *iterFlag
+	fmt.Printf("Number of iterations: %v\n", iter)
+
+	yUnenc := [][]float64{}
+	uUnenc := [][]float64{}
+	xcUnenc := [][]float64{}
+
+	// plant 의 shadow 제어기와 같은 pid.Controller
+	baseline, err := pid.NewController(sp.StateSpace(), x_ini)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := 0; i < iter; i++ {
+		y := yTest
+		u := baseline.Step(y)
+
+		yUnenc = append(yUnenc, y)
+		uUnenc = append(uUnenc, u)
+		xcUnenc = append(xcUnenc, baseline.X)
+	}
+
+	// ================= 10) Simulation: encrypted with recovered_* =================
+	yEnc := [][]float64{}
+	uEnc := [][]float64{}
+	period := make([][]float64, iter)
+	startPeriod := make([]time.Time, iter)
+
+	// start from recovered packed state
+	// xCtPack := recoveredX
+
+	for i := 0; i < iter; i++ {
+		y := yTest
+		startPeriod[i] = time.Now()
+
+		// Encrypt y with recovered secret key
+		yBar := utils.RoundVec(utils.ScalVecMult(1/r, y))
+		yCtPack := RLWE.EncPack(yBar, tau, 1/L, *encryptorRLWE2, ringQ, params)
+
+		// Unpack state and input
+		xCt := RLWE.UnpackCt(xCtPack, n, tau, evaluatorRLWE2, ringQ, monomials, params)
+		yCt := RLWE.UnpackCt(yCtPack, p, tau, evaluatorRLWE2, ringQ, monomials, params)
+
+		// Controller output: u = Hx + Jy
+		uCtPack := RGSW.MultPack(xCt, recoveredH, evaluatorRGSW2, ringQ, params)
+		JyCt := RGSW.MultPack(yCt, recoveredJ, evaluatorRGSW2, ringQ, params)
+		uCtPack = RLWE.Add(uCtPack, JyCt, zeroCt, params)
+
+		// Decrypt output
+		u := RLWE.DecUnpack(uCtPack, m, tau, *decryptorRLWE2, r*s*s*L, ringQ, params)
+
+		// State update: x = F*x + G*y
+		FxCt := RGSW.MultPack(xCt, recoveredF, evaluatorRGSW2, ringQ, params)
+		GyCt := RGSW.MultPack(yCt, recoveredG, evaluatorRGSW2, ringQ, params)
+		xCtPack = RLWE.Add(FxCt, GyCt, zeroCt, params)
+
+		period[i] = []float64{float64(time.Since(startPeriod[i]).Nanoseconds()) / 1e6}
+
+		yEnc = append(yEnc, y)
+		uEnc = append(uEnc, u)
+	}
+
+	avgPeriod := utils.Average(utils.MatToVec(period))
+	fmt.Println("Average elapsed time for a control period (recovered):", avgPeriod, "ms")
+
+	// ================= 11) Compare =================
+	uDiff := make([][]float64, iter)
+	for i := range uDiff {
+		uDiff[i] = []float64{utils.Vec2Norm(utils.VecSub(uUnenc[i], uEnc[i]))}
+	}
+
+	// 시뮬레이션 결과는 출력 폴더 (controller/, plant/ 옆) 에 저장, 저장소의 CSV 는 건드리지 않음
+	utils.DataExport(uUnenc, filepath.Join(base, "uUnenc.csv"))
+	utils.DataExport(uEnc, filepath.Join(base, "uEnc.csv"))
+	utils.DataExport(uDiff, filepath.Join(base, "uDiff.csv"))
+	fmt.Println("[SAVE] simulation CSVs:", base)
+	// utils.DataExport(period, filepath.Join(base, "period.csv"))
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	com_utils "Encrypted_Cartpole/03_Utils"
+	"Encrypted_Cartpole/03_Utils/spec"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	utils "github.com/CDSL-EncryptedControl/CDSL/utils"
+	RGSW "github.com/CDSL-EncryptedControl/CDSL/utils/core/RGSW"
+	RLWE "github.com/CDSL-EncryptedControl/CDSL/utils/core/RLWE"
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// 외부곱 병렬화 (controller -workers, com_utils.ExtPool) 의 LogN 별 speed-up
+//
+//	go run multbench.go
+//	go run multbench.go -spec ../config/cartpole_N12.json -workers 4 -iter 50
+//
+// spec 마다 메모리에서 키 / pack 을 만들고 같은 y 열로 직렬 (한 코어) 제어기와 병렬 제어기를 돌림
+// 상태는 keygen bundle 과 같은 slot 상태 (ctFGslot, controller 가 실제로 쓰는 경로) 와 예전 packed 상태 둘 다
+// lattigo 경로 (UnpackCt + MultPack / 외부곱 + RLWE.Add) 도 같이 돌려서 com_utils.Kernel 이 같은 값을 내는지 확인
+// 스텝마다 u 와 새 상태가 비트 단위로 같은지 확인하고 Output + Update 평균 시간 비교 (다르면 exit 1)
+var (
+	specPaths = flag.String("spec", strings.Join([]string{
+		filepath.Join("..", "config", "cartpole_N10.json"),
+		filepath.Join("..", "config", "cartpole_N11.json"),
+		filepath.Join("..", "config", "cartpole_N12.json"),
+	}, ","), "comma separated controller specs (one row per LogN)")
+	workers = flag.Int("workers", 0, "parallel workers (0 = number of CPUs)")
+	iters   = flag.Int("iter", 30, "control steps per spec")
+)
+
+type benchResult struct {
+	logN, tau, terms int
+	mode             string        // slot / packed
+	lattigo          time.Duration // 스텝당 Output + Update 의 lattigo 외부곱과 덧셈
+	serial, parallel time.Duration // 스텝당 Output + Update
+	identical        bool
+}
+
+func bench(path string, w int, slot bool) (*benchResult, error) {
+	sp, err := spec.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	params, err := sp.RLWEParams()
+	if err != nil {
+		return nil, err
+	}
+	F, G, H, _, J := sp.Matrices()
+	n, _, p := sp.Dims()
+	tau := sp.Tau()
+	s, L, r := sp.Scales.S, sp.Scales.L, sp.Scales.R
+	levelQ, levelP := params.QCount()-1, params.PCount()-1
+	ringQ := params.RingQ()
+
+	// keygen.go 와 같은 방식의 pack (벤치마크용 키, 저장하지 않음)
+	monomials, galEls := com_utils.PackSetup(params, tau)
+	kgen := rlwe.NewKeyGenerator(params)
+	sk := kgen.GenSecretKeyNew()
+	rlk := kgen.GenRelinearizationKeyNew(sk)
+	gks := kgen.GenGaloisKeysNew(galEls, sk)
+	encRLWE := rlwe.NewEncryptor(params, sk)
+	encRGSW := rgsw.NewEncryptor(params, sk)
+
+	GBar := utils.ScalMatMult(1/s, G)
+	ctF := RGSW.EncPack(F, tau, encRGSW, levelQ, levelP, ringQ, params)
+	ctG := RGSW.EncPack(GBar, tau, encRGSW, levelQ, levelP, ringQ, params)
+	ctH := RGSW.EncPack(utils.ScalMatMult(1/s, H), tau, encRGSW, levelQ, levelP, ringQ, params)
+	ctJ := RGSW.EncPack(utils.ScalMatMult(1/(s*s), J), tau, encRGSW, levelQ, levelP, ringQ, params)
+	xBar := utils.RoundVec(utils.ScalVecMult(1/(r*s), sp.XIni))
+	xCt := RLWE.EncPack(xBar, tau, 1/L, *encRLWE, ringQ, params)
+
+	serial := com_utils.NewEncController(params, n, p, tau, ctF, ctG, ctH, ctJ, xCt, rlk, gks)
+	entries, ctFG := com_utils.EncSlotPack(F, GBar, encRGSW, levelQ, levelP, params)
+	if slot {
+		if err := serial.SetSlotPack(entries, ctFG); err != nil {
+			return nil, err
+		}
+	}
+	par := serial.NewSession()
+	par.SetWorkers(w)
+	defer par.Close()
+
+	evalRGSW := rgsw.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk))
+	evalRLWE := rlwe.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk, gks...))
+	zero := rlwe.NewCiphertext(params, 1)
+	xRef := xCt.CopyNew()
+	var xsRef []*rlwe.Ciphertext // slot 상태 (SetSlotPack 처럼 x0 를 한 번 unpack)
+	if slot {
+		xsRef = RLWE.UnpackCt(xCt.CopyNew(), n, tau, evalRLWE, ringQ, monomials, params)
+	}
+
+	res := &benchResult{logN: params.LogN(), tau: tau, terms: 2 * (n + p), mode: "packed", identical: true}
+	if slot {
+		res.mode, res.terms = "slot", n+p+len(entries)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for k := 0; k < *iters; k++ {
+		y := make([]float64, p)
+		for i := range y {
+			y[i] = 4*rng.Float64() - 2
+		}
+		yCt := RLWE.EncPack(utils.RoundVec(utils.ScalVecMult(1/r, y)), tau, 1/L, *encRLWE, ringQ, params)
+
+		// lattigo 경로 (UnpackCt 가 입력을 스케일하므로 y 는 복사본)
+		xs := xsRef
+		if !slot {
+			xs = RLWE.UnpackCt(xRef, n, tau, evalRLWE, ringQ, monomials, params)
+		}
+		ys := RLWE.UnpackCt(yCt.CopyNew(), p, tau, evalRLWE, ringQ, monomials, params)
+		t := time.Now()
+		uRef := RLWE.Add(RGSW.MultPack(xs, ctH, evalRGSW, ringQ, params), RGSW.MultPack(ys, ctJ, evalRGSW, ringQ, params), zero, params)
+		if slot {
+			xsRef = slotUpdate(xs, ys, entries, ctFG, evalRGSW, params)
+		} else {
+			xRef = RLWE.Add(RGSW.MultPack(xs, ctF, evalRGSW, ringQ, params), RGSW.MultPack(ys, ctG, evalRGSW, ringQ, params), zero, params)
+		}
+		res.lattigo += time.Since(t)
+
+		// 제어기의 u 는 다음 Output 까지만 유효 → 바로 비교
+		var uS, uP *rlwe.Ciphertext
+		for _, c := range []struct {
+			ctrl *com_utils.EncController
+			u    **rlwe.Ciphertext
+			d    *time.Duration
+		}{{serial, &uS, &res.serial}, {par, &uP, &res.parallel}} {
+			xs, ys := c.ctrl.Unpack(yCt)
+			t := time.Now()
+			*c.u = c.ctrl.Output(xs, ys)
+			c.ctrl.Update(xs, ys)
+			*c.d += time.Since(t)
+		}
+		if !sameValue(uS, uRef) || !sameValue(uP, uRef) {
+			res.identical = false
+		}
+		if slot {
+			for i := range xsRef {
+				if !sameValue(serial.Xs[i], xsRef[i]) || !sameValue(par.Xs[i], xsRef[i]) {
+					res.identical = false
+				}
+			}
+		} else if !sameValue(serial.X, xRef) || !sameValue(par.X, xRef) {
+			res.identical = false
+		}
+	}
+	res.lattigo /= time.Duration(*iters)
+	res.serial /= time.Duration(*iters)
+	res.parallel /= time.Duration(*iters)
+	return res, nil
+}
+
+// lattigo 로 slot 상태 갱신: x_k = Σ [F | Ḡ]_kj ⊠ (x | y)_j (행 순서, 외부곱마다 새 암호문)
+func slotUpdate(xs, ys []*rlwe.Ciphertext, entries []com_utils.SlotEntry, fg []*rgsw.Ciphertext, eval *rgsw.Evaluator, params rlwe.Parameters) []*rlwe.Ciphertext {
+	ringQ := params.RingQ()
+	next := make([]*rlwe.Ciphertext, len(xs))
+	for k := range next {
+		next[k] = rlwe.NewCiphertext(params, 1)
+	}
+	for i, e := range entries {
+		var ct *rlwe.Ciphertext
+		if e.Col < len(xs) {
+			ct = xs[e.Col]
+		} else {
+			ct = ys[e.Col-len(xs)]
+		}
+		prod := rlwe.NewCiphertext(params, 1)
+		eval.ExternalProduct(ct, fg[i], prod)
+		ringQ.Add(next[e.Row].Value[0], prod.Value[0], next[e.Row].Value[0])
+		ringQ.Add(next[e.Row].Value[1], prod.Value[1], next[e.Row].Value[1])
+	}
+	return next
+}
+
+// 다항식만 비교 (제어기 버퍼와 lattigo 결과는 메타데이터 출처가 다름)
+func sameValue(a, b *rlwe.Ciphertext) bool {
+	if len(a.Value) != len(b.Value) {
+		return false
+	}
+	for i := range a.Value {
+		if !a.Value[i].Equal(&b.Value[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func main() {
+	flag.Parse()
+	w := *workers
+	if w <= 0 {
+		w = runtime.NumCPU()
+	}
+	fmt.Printf("[MULT] %d workers, %d CPUs, %d steps per spec (Output + Update per step)\n", w, runtime.NumCPU(), *iters)
+	fmt.Printf("%-5s %-6s %4s %6s %11s %11s %11s %8s %s\n", "logN", "state", "tau", "terms", "lattigo ms", "serial ms", "parallel ms", "speed-up", "bit-identical")
+
+	ok := true
+	for _, path := range strings.Split(*specPaths, ",") {
+		for _, slot := range []bool{true, false} {
+			res, err := bench(strings.TrimSpace(path), w, slot)
+			if err != nil {
+				log.Fatalf("%s: %v", path, err)
+			}
+			fmt.Printf("%-5d %-6s %4d %6d %11.3f %11.3f %11.3f %7.2fx %v\n", res.logN, res.mode, res.tau, res.terms,
+				float64(res.lattigo)/1e6, float64(res.serial)/1e6, float64(res.parallel)/1e6, float64(res.serial)/float64(res.parallel), res.identical)
+			ok = ok && res.identical
+		}
+	}
+	if !ok {
+		log.Fatal("controller result differs from MultPack")
+	}
+}
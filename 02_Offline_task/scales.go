@@ -0,0 +1,82 @@
+package main
+
+import (
+	"Encrypted_Cartpole/03_Utils/quant"
+	"Encrypted_Cartpole/03_Utils/spec"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 양자화 스케일 r, s, L 자동 선택
+//
+//	go run scales.go -spec ../config/cartpole_N12.json
+//	go run scales.go -spec ../config/cartpole_N12.json -o ../config/cartpole_N12_auto.json
+//
+// spec 의 현재 스케일도 같은 기준으로 평가해서 같이 출력
+var (
+	specPath   = flag.String("spec", filepath.Join("..", "config", "cartpole_N12.json"), "controller spec file")
+	yMaxFlag   = flag.String("ymax", "40,200", "bound on |y| per plant output (angle, position)")
+	horizon    = flag.Int("horizon", 300, "closed-loop steps the integer state must stay in range")
+	target     = flag.Float64("target", 1, "allowed |u_enc - u| (quantization + noise)")
+	margin     = flag.Float64("margin", 4, "required headroom below Q/2 in bits")
+	noiseTrial = flag.Int("noise", 20, "encryptions used to measure noise (0 = ignore noise)")
+	outPath    = flag.String("o", "", "write a copy of the spec with the selected scales")
+)
+
+func main() {
+	flag.Parse()
+
+	sp, err := spec.Load(*specPath)
+	if err != nil {
+		log.Fatalf("load spec: %v", err)
+	}
+	params, err := sp.RLWEParams()
+	if err != nil {
+		log.Fatalf("params: %v", err)
+	}
+
+	pb := quant.DefaultProblem(sp)
+	pb.YMax = nil
+	for _, f := range strings.Split(*yMaxFlag, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			log.Fatalf("bad -ymax %q: %v", *yMaxFlag, err)
+		}
+		pb.YMax = append(pb.YMax, v)
+	}
+	pb.Horizon = *horizon
+	pb.TargetErr = *target
+	pb.MarginBits = *margin
+	if *noiseTrial > 0 {
+		pb.Noise = quant.MeasureNoise(params, sp.Tau(), *noiseTrial)
+		fmt.Printf("Noise σ (N=2^%d): fresh %.1f, slot %.1f, external product %.1f\n",
+			params.LogN(), pb.Noise.Fresh, pb.Noise.Slot, pb.Noise.Ext)
+	}
+
+	cur, err := quant.Evaluate(pb, sp.Scales)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Current :", cur)
+
+	best, err := quant.Select(pb)
+	if err != nil {
+		if best.TotalErr > 0 {
+			fmt.Println("Closest :", best)
+		}
+		log.Fatal(err)
+	}
+	fmt.Println("Selected:", best)
+
+	if *outPath != "" {
+		sp.Scales = best.Scales
+		if err := spec.Save(*outPath, sp); err != nil {
+			log.Fatalf("save spec: %v", err)
+		}
+		fmt.Println("[SCALES] wrote", *outPath)
+	}
+}
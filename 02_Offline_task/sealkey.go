@@ -0,0 +1,56 @@
+package main
+
+import (
+	com_utils "Encrypted_Cartpole/03_Utils"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// 예전 keygen 이 만든 평문 sk.dat 을 passphrase 로 암호화된 sk.enc 로 바꿈
+//
+//	go run sealkey.go -dir enc_data/rgsw_for_N12/plant
+var dir = flag.String("dir", filepath.Join("enc_data", "rgsw_for_N12", "plant"), "plant bundle holding a plaintext sk.dat")
+
+func main() {
+	flag.Parse()
+
+	bundle, err := com_utils.OpenBundle(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if bundle.Manifest.Role != com_utils.RolePlant {
+		log.Fatalf("%s is a %q bundle, want %q", *dir, bundle.Manifest.Role, com_utils.RolePlant)
+	}
+
+	// manifest 해시 확인 후 평문 키 로드
+	sk := new(rlwe.SecretKey)
+	if err := bundle.ReadRT("sk.dat", sk); err != nil {
+		log.Fatalf("load sk.dat: %v", err)
+	}
+
+	passphrase, err := com_utils.Passphrase("New passphrase for the plant secret key: ", true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := com_utils.WriteSealedKey(filepath.Join(*dir, com_utils.SecretKeyFile), sk, passphrase); err != nil {
+		log.Fatalf("save %s: %v", com_utils.SecretKeyFile, err)
+	}
+
+	// manifest 갱신 후 평문 키 삭제
+	delete(bundle.Manifest.Files, "sk.dat")
+	if err := bundle.Manifest.HashFiles(*dir, com_utils.SecretKeyFile); err != nil {
+		log.Fatal(err)
+	}
+	if err := com_utils.WriteManifest(*dir, bundle.Manifest); err != nil {
+		log.Fatalf("save manifest: %v", err)
+	}
+	if err := os.Remove(filepath.Join(*dir, "sk.dat")); err != nil {
+		log.Fatalf("remove sk.dat: %v", err)
+	}
+	fmt.Println("[SEAL] wrote", filepath.Join(*dir, com_utils.SecretKeyFile), "and removed sk.dat")
+}
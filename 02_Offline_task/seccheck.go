@@ -0,0 +1,77 @@
+package main
+
+import (
+	"Encrypted_Cartpole/03_Utils/security"
+	"Encrypted_Cartpole/03_Utils/spec"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// RLWE 파라미터 보안 수준 확인 (HE standard 표)
+//
+//	go run seccheck.go                                 (../config/*.json 전부)
+//	go run seccheck.go ../config/cartpole_N12.json
+//	go run seccheck.go -logN 11 -logQ 28 -logP 28
+//
+// 하나라도 -min 을 만족하지 않으면 exit code 1
+var (
+	logNFlag = flag.Int("logN", 0, "check a literal instead of spec files")
+	logQFlag = flag.String("logQ", "", "literal logQ, comma separated")
+	logPFlag = flag.String("logP", "", "literal logP, comma separated")
+	minBits  = flag.Int("min", 128, "required security level in bits")
+)
+
+func main() {
+	flag.Parse()
+
+	type target struct {
+		name   string
+		params spec.Params
+	}
+	targets := []target{}
+
+	if *logNFlag > 0 {
+		var p spec.Params
+		if err := p.Override(*logNFlag, *logQFlag, *logPFlag); err != nil {
+			log.Fatal(err)
+		}
+		targets = append(targets, target{"literal", p})
+	} else {
+		files := flag.Args()
+		if len(files) == 0 {
+			var err error
+			files, err = filepath.Glob(filepath.Join("..", "config", "*.json"))
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		for _, fn := range files {
+			sp, err := spec.Load(fn)
+			if err != nil {
+				log.Fatal(err)
+			}
+			targets = append(targets, target{fn, sp.Params})
+		}
+	}
+
+	failed := 0
+	for _, t := range targets {
+		rep, err := security.EstimateLiteral(t.params.Literal())
+		switch {
+		case err != nil:
+			fmt.Printf("[UNKNOWN] %s: %v\n", t.name, err)
+			failed++
+		case !rep.Secure(*minBits):
+			fmt.Printf("[FAIL]    %s: %s\n", t.name, rep)
+			failed++
+		default:
+			fmt.Printf("[OK]      %s: %s\n", t.name, rep)
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	com_utils "Encrypted_Cartpole/03_Utils"
+	"Encrypted_Cartpole/03_Utils/analysis"
+	"Encrypted_Cartpole/03_Utils/pid"
+	"Encrypted_Cartpole/03_Utils/spec"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	utils "github.com/CDSL-EncryptedControl/CDSL/utils"
+)
+
+// 완성된 bundle 자체 검증: 평문 제어기 vs 암호 제어기 (keygen 9~11 단계와 같은 비교)
+//
+//	go run verify.go -bundle enc_data/rgsw_for_N12
+//	go run verify.go -bundle enc_data/rgsw_for_N12 -y-csv y.csv -max-udiff 0.2
+//	go run verify.go -bundle enc_data/rgsw_for_N12 -model offline -input 0
+//
+// y 입력: -y (상수), -y-csv (한 줄에 y0,y1, 짧으면 반복), -model offline (offline_rlwe.go plant 를 암호 u 로 구동)
+// max |uDiff| > -max-udiff 이거나 스텝 시간 최대값 > -max-step-ms 이면 exit code 1
+var (
+	bundleDir  = flag.String("bundle", filepath.Join("enc_data", "rgsw_for_N12"), "keygen output holding controller/ and plant/")
+	specPath   = flag.String("spec", "", "controller spec (default <bundle>/controller/spec.json)")
+	iterFlag   = flag.Int("iter", 500, "control steps")
+	yFlag      = flag.String("y", "-2,2", "constant plant output")
+	yCSV       = flag.String("y-csv", "", "CSV of plant outputs per step (overrides -y)")
+	model      = flag.String("model", "", "drive a plant model with the encrypted u instead of a y sequence (offline)")
+	inputCol   = flag.Int("input", 0, "plant input (column of B) driven by u for -model")
+	maxUDiff   = flag.Float64("max-udiff", 0.5, "fail if max |u_enc - u| exceeds this")
+	maxStepMs  = flag.Float64("max-step-ms", 30, "fail if the slowest encrypted step exceeds this (ms)")
+	csvDir     = flag.String("csv", "", "write uUnenc.csv, uEnc.csv, uDiff.csv here (empty = skip)")
+	unsafeSeed = flag.String("unsafe-seed", "", "UNSAFE, tests only: deterministic y encryption (reproducible uEnc.csv)")
+)
+
+func parseFloats(str string) ([]float64, error) {
+	out := []float64{}
+	for _, f := range strings.Split(str, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad float list %q: %w", str, err)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func readYCSV(path string, p int) ([][]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rd := csv.NewReader(f)
+	rd.FieldsPerRecord = -1
+	rows, err := rd.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	ys := [][]float64{}
+	for i, row := range rows {
+		y, err := parseFloats(strings.Join(row, ","))
+		if err != nil {
+			if i == 0 {
+				continue // 헤더
+			}
+			return nil, fmt.Errorf("%s line %d: %w", path, i+1, err)
+		}
+		if len(y) != p {
+			return nil, fmt.Errorf("%s line %d: want %d values, got %d", path, i+1, p, len(y))
+		}
+		ys = append(ys, y)
+	}
+	if len(ys) == 0 {
+		return nil, fmt.Errorf("%s: no rows", path)
+	}
+	return ys, nil
+}
+
+func main() {
+	flag.Parse()
+	// 결정적 모드: y 암호화 sampler 에만 seed (TLS 는 crypto/rand 그대로)
+	var seed *com_utils.UnsafeSeed
+	if *unsafeSeed != "" {
+		s, err := com_utils.NewUnsafeSeed(*unsafeSeed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		seed = s
+		log.Println(com_utils.UnsafeSeedWarning)
+	}
+
+	ctrlDir := filepath.Join(*bundleDir, com_utils.RoleController)
+	plantDir := filepath.Join(*bundleDir, com_utils.RolePlant)
+	if *specPath == "" {
+		*specPath = filepath.Join(ctrlDir, spec.FileName)
+	}
+	sp, err := spec.Load(*specPath)
+	if err != nil {
+		log.Fatalf("load spec: %v", err)
+	}
+
+	// ===== bundle 로드 (해시/spec 확인) =====
+	ctrlBundle, err := com_utils.OpenControllerBundle(ctrlDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	plantBundle, err := com_utils.OpenPlantBundle(plantDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, b := range []*com_utils.Bundle{ctrlBundle, plantBundle} {
+		if err := b.CheckSpec(sp); err != nil {
+			log.Fatal(err)
+		}
+		if b.Manifest.UnsafeSeed {
+			log.Printf("%s (bundle %s)", com_utils.UnsafeSeedWarning, b.Dir)
+		}
+	}
+	ctrl, err := ctrlBundle.LoadController()
+	if err != nil {
+		log.Fatal(err)
+	}
+	passphrase, err := com_utils.Passphrase("Plant secret key passphrase: ", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	codec, err := plantBundle.LoadPlantCodec(passphrase)
+	if err != nil {
+		log.Fatalf("load sk: %v", err)
+	}
+	if err := codec.UseUnsafeSeed(seed); err != nil {
+		log.Fatal(err)
+	}
+	shadow, err := pid.NewController(sp.StateSpace(), sp.XIni)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// ===== y 입력 =====
+	_, _, p := sp.Dims()
+	var ySeq [][]float64
+	var plant *analysis.Plant
+	var xp []float64
+	switch {
+	case *model == "offline":
+		pl, err := analysis.OfflinePlant().Input(*inputCol)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pl.ErrorOutput = true // 아두이노와 같이 오차 (0 - y) 를 제어기에 보냄
+		plant = &pl
+		xp = make([]float64, len(pl.A))
+	case *model != "":
+		log.Fatalf("unknown -model %q (want offline)", *model)
+	case *yCSV != "":
+		if ySeq, err = readYCSV(*yCSV, p); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		y, err := parseFloats(*yFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(y) != p {
+			log.Fatalf("-y: want %d values, got %d", p, len(y))
+		}
+		ySeq = [][]float64{y}
+	}
+
+	// ===== 평문 / 암호 루프 =====
+	iter := *iterFlag
+	uUnenc := make([][]float64, iter)
+	uEnc := make([][]float64, iter)
+	uDiff := make([][]float64, iter)
+	stepMs := make([]float64, iter)
+	maxDiff := 0.0
+	for i := 0; i < iter; i++ {
+		var y []float64
+		if plant != nil {
+			y = plant.Output(xp)
+		} else {
+			y = ySeq[i%len(ySeq)]
+		}
+
+		uUnenc[i] = shadow.Step(y)
+
+		// plant → controller 는 실제와 같은 seed 압축 형식
+		t := time.Now()
+		ySeeded, err := codec.EncryptYSeeded(y)
+		if err != nil {
+			log.Fatal(err)
+		}
+		yCtPack, err := ySeeded.Expand(ctrl.Params)
+		if err != nil {
+			log.Fatal(err)
+		}
+		uEnc[i] = codec.DecryptU(ctrl.Step(yCtPack))
+		stepMs[i] = float64(time.Since(t)) / 1e6
+
+		uDiff[i] = []float64{utils.Vec2Norm(utils.VecSub(uUnenc[i], uEnc[i]))}
+		if d := uDiff[i][0]; d > maxDiff || math.IsNaN(d) {
+			maxDiff = d
+		}
+
+		// plant 는 암호 제어기 출력으로 구동 (plant 쪽 shadow 비교와 같은 구성)
+		if plant != nil {
+			next := make([]float64, len(xp))
+			for r := range next {
+				for c, v := range plant.A[r] {
+					next[r] += v * xp[c]
+				}
+				for c, v := range plant.B[r] {
+					next[r] += v * uEnc[i][c]
+				}
+			}
+			xp = next
+		}
+	}
+
+	if *csvDir != "" {
+		if err := com_utils.EnsureDir(*csvDir); err != nil {
+			log.Fatal(err)
+		}
+		utils.DataExport(uUnenc, filepath.Join(*csvDir, "uUnenc.csv"))
+		utils.DataExport(uEnc, filepath.Join(*csvDir, "uEnc.csv"))
+		utils.DataExport(uDiff, filepath.Join(*csvDir, "uDiff.csv"))
+	}
+
+	// ===== 판정 =====
+	sorted := append([]float64(nil), stepMs...)
+	sort.Float64s(sorted)
+	maxStep, p99 := 0.0, 0.0
+	if iter > 0 {
+		maxStep = sorted[iter-1]
+		p99 = sorted[(iter*99)/100]
+	}
+	fmt.Printf("[VERIFY] %s: %d steps, max |uDiff| = %.4g (limit %g), step avg %.3f / p99 %.3f / max %.3f ms (limit %g)\n",
+		*bundleDir, iter, maxDiff, *maxUDiff, utils.Average(stepMs), p99, maxStep, *maxStepMs)
+
+	failed := false
+	if !(maxDiff <= *maxUDiff) {
+		fmt.Println("[FAIL] max |uDiff| over limit")
+		failed = true
+	}
+	if maxStep > *maxStepMs {
+		fmt.Println("[FAIL] encrypted step time over limit")
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+}
@@ -0,0 +1,87 @@
+package com_utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// 제어 루프 hot path 가 스텝마다 할당하지 않는지 (세션 로그의 allocs/step 은 프로세스 전체라 여기서 함수 단위로)
+
+func TestHotPathAllocs(t *testing.T) {
+	tb := newTestBundle(t, "cartpole_N10.json")
+	params, codec, base := tb.params, tb.codec, tb.ctrl
+	_, m, p := tb.sp.Dims()
+	y := make([]float64, p)
+	u := make([]float64, m)
+	ySeeded := codec.NewSeeded()
+	var recv SeededCiphertext
+	var wire bytes.Buffer
+	yCt := rlwe.NewCiphertext(params, 1)
+
+	slot := tb.slotSession(t)
+	slotPar := tb.slotSession(t)
+	slotPar.SetWorkers(3)
+	defer slotPar.Close()
+	pooled := base.NewSession()
+	pooled.SetWorkers(2)
+	defer pooled.Close()
+	shared := NewExtWorkers(2)
+	defer shared.Close()
+	sharedSess := base.NewSession()
+	sharedSess.ShareWorkers(shared)
+	defer sharedSess.Close()
+	async := base.NewSession()
+	defer async.Close()
+
+	cases := []struct {
+		name string
+		step func()
+	}{
+		{"packed", func() { base.Step(yCt) }},
+		{"slot", func() { slot.Step(yCt) }},
+		{"slot workers", func() { slotPar.Step(yCt) }},
+		{"workers", func() { pooled.Step(yCt) }},
+		{"shared workers", func() { sharedSess.Step(yCt) }},
+		{"async update", func() {
+			xs, ys := async.Unpack(yCt)
+			async.Output(xs, ys)
+			<-async.UpdateAsync(xs, ys)
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// plant 암호화 → 직렬화 → controller 복원 → 스텝 → plant 복호화 한 바퀴
+			loop := func() {
+				if err := codec.EncryptYSeededInto(y, ySeeded); err != nil {
+					t.Fatal(err)
+				}
+				wire.Reset()
+				if _, err := ySeeded.WriteTo(&wire); err != nil {
+					t.Fatal(err)
+				}
+				if err := recv.UnmarshalBinary(wire.Bytes()); err != nil {
+					t.Fatal(err)
+				}
+				if err := recv.ExpandInto(params, yCt); err != nil {
+					t.Fatal(err)
+				}
+				tc.step()
+			}
+			loop() // 첫 스텝은 버퍼 / 메타데이터 캐시를 잡음
+			if a := testing.AllocsPerRun(20, loop); a != 0 {
+				t.Errorf("%.1f allocs per step", a)
+			}
+		})
+	}
+
+	if a := testing.AllocsPerRun(20, func() { codec.EncryptYSeededInto(y, ySeeded) }); a != 0 {
+		t.Errorf("EncryptYSeededInto: %.1f allocs", a)
+	}
+	uCt := base.Step(yCt)
+	codec.DecryptUInto(uCt, u)
+	if a := testing.AllocsPerRun(20, func() { codec.DecryptUInto(uCt, u) }); a != 0 {
+		t.Errorf("DecryptUInto: %.1f allocs", a)
+	}
+}
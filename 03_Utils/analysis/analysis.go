@@ -0,0 +1,421 @@
+// 폐루프 오차 상한 계산 (README 의 ||u|| < 0.1901 조건)
+//
+// plant   x_p' = A x_p + B u,        y = C x_p  (ErrorOutput 이면 y = 0 - C x_p)
+// 제어기  x_c' = F x_c + G (y + δy) + δx
+//
+//	u    = H x_c + J (y + δy) + δu
+//
+// 제어기 행렬은 keygen 과 같이 정수화한 값 (G/s, H/s, J/s² 를 0 방향 절삭 후 다시 s 배) 을 사용
+// δy: 출력 반올림 (r/2) + y 암호문 noise, δx: 상태 갱신 외부곱 noise, δu: 출력 외부곱 noise
+//
+// 폐루프가 안정하면 |δ| 가 매 스텝 최대여도 u 의 편차는
+//
+//	sup |Δu| ≤ Σ_k |C_cl A_cl^k B_w| w̄ + |D_w| w̄   (ℓ1 norm of the impulse response)
+//
+// 로 유계이고, 이 값이 Margin 안에 있는지 확인
+package analysis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"Encrypted_Cartpole/03_Utils/quant"
+	"Encrypted_Cartpole/03_Utils/spec"
+)
+
+// README 의 안정성 여유
+const DefaultMargin = 0.1901
+
+// 이산시간 plant 모델
+//
+// ErrorOutput: 제어기 입력이 target - C x_p (카트폴, ardu.ino 가 각도/위치 오차를 보냄, target = 0)
+// false 면 y = C x_p 그대로 (MATLAB four-tank 설계, u = K x̂ 가 음의 feedback 을 이미 포함)
+type Plant struct {
+	A, B, C     [][]float64
+	ErrorOutput bool `json:"errorOutput"`
+}
+
+// 제어기가 받는 y 의 행렬 (ErrorOutput 이면 -C)
+func (pl Plant) OutputMatrix() [][]float64 {
+	if pl.ErrorOutput {
+		return scale(-1, pl.C)
+	}
+	return pl.C
+}
+
+// 제어기가 받는 y
+func (pl Plant) Output(xp []float64) []float64 {
+	C := pl.OutputMatrix()
+	y := make([]float64, len(C))
+	for i, row := range C {
+		for j, v := range row {
+			y[i] += v * xp[j]
+		}
+	}
+	return y
+}
+
+// plant 모델 파일 ({"A": .., "B": .., "C": .., "errorOutput": true}) 로드
+func LoadPlant(path string) (Plant, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Plant{}, err
+	}
+	var pl Plant
+	if err := json.Unmarshal(b, &pl); err != nil {
+		return Plant{}, fmt.Errorf("parse plant %s: %w", path, err)
+	}
+	if len(pl.A) == 0 || len(pl.B) != len(pl.A) || len(pl.C) == 0 || len(pl.C[0]) != len(pl.A) || len(pl.B[0]) == 0 {
+		return Plant{}, fmt.Errorf("plant %s: A, B, C dimensions do not match", path)
+	}
+	return pl, nil
+}
+
+// offline_rlwe.go 에 있던 plant 모델 (입력 2, 출력 2)
+// conversion_RGSW.m 의 four-tank 를 Ts = 0.1 로 이산화한 것 → 카트폴 모델이 아니므로 카트폴 PID 의 안정성은 판단할 수 없음
+func OfflinePlant() Plant {
+	return Plant{
+		A: [][]float64{
+			{0.998406460921939, 0, 0.00417376927758289, 0},
+			{0, 0.998893625478993, 0, -0.00332671872292611},
+			{0, 0, 0.995822899329324, 0},
+			{0, 0, 0, 0.996671438596397},
+		},
+		B: [][]float64{
+			{0.00831836513049678, 9.99686131895421e-06},
+			{-5.19664522845810e-06, 0.00627777465144397},
+			{0, 0.00477571210746992},
+			{0.00311667643652227, 0},
+		},
+		C: [][]float64{
+			{0.500000000000000, 0, 0, 0},
+			{0, 0.500000000000000, 0, 0},
+		},
+	}
+}
+
+// B 의 입력 하나만 사용하는 plant (제어 입력이 1개인 PID 용)
+func (pl Plant) Input(col int) (Plant, error) {
+	if col < 0 || col >= len(pl.B[0]) {
+		return Plant{}, fmt.Errorf("plant has %d inputs, no input %d", len(pl.B[0]), col)
+	}
+	B := make([][]float64, len(pl.B))
+	for i := range pl.B {
+		B[i] = []float64{pl.B[i][col]}
+	}
+	return Plant{A: pl.A, B: B, C: pl.C, ErrorOutput: pl.ErrorOutput}, nil
+}
+
+// offline_rlwe.go 의 four-tank 제어기 (MATLAB 설계를 ARX 로 바꾼 Hy, Hu, 벡터화 형식) 와 스케일
+//
+// 상태 = [u(k-4..k-1); y(k-4..k-1)], u(k) = Hu U + Hy Y, y = C x_p (ErrorOutput 아님)
+// 저장소의 제어기 중 plant 모델이 있는 유일한 설계라 부호 규약 확인용
+func FourTankProblem() Problem {
+	vecHy := [][]float64{
+		{0.334883269997112, -0.0993726952581632, 0.109105860257554, 0.340141173304891},
+		{0.340715074862138, -0.101693452659005, 0.111263681570879, 0.346096102431116},
+		{0.0212757993084255, -0.00721494759029773, 0.00717571762620109, 0.0215259945842975},
+		{-0.705323732730193, 0.209355413587286, -0.230615165512593, -0.715776671026420},
+	}
+	vecHu := [][]float64{
+		{-0.285602015399616, -0.000307101965816320, 0.00106747945670671, -0.286337872976116},
+		{0.183962668144521, -0.000156850543232820, 0.000585408816047406, 0.183342919294642},
+		{0.464731844320360, -0.000717550250832144, 0.000183250207538066, 0.464698956437188},
+		{0.631884279880355, -0.00124460838502882, -0.000477508261005455, 0.632382252336539},
+	}
+	const hist, m, p = 4, 2, 2 // 과거 샘플 수, u 차원, y 차원 (h = max(m, p) = 2)
+
+	// 벡터화 해제: 행 i, 칸 h·j+c = Hu_i(j, c) → H = [Hu | Hy] (열 블록 i 가 과거 샘플 i)
+	nu, n := hist*m, hist*(m+p)
+	H := zeros(m, n)
+	for i := 0; i < hist; i++ {
+		for j := 0; j < m; j++ {
+			for c := 0; c < m; c++ {
+				H[j][m*i+c] = vecHu[i][m*j+c]
+			}
+			for c := 0; c < p; c++ {
+				H[j][nu+p*i+c] = vecHy[i][m*j+c]
+			}
+		}
+	}
+	// shift register, 마지막 u 블록은 새 u(k) = H x, 마지막 y 블록은 새 y(k)
+	F := zeros(n, n)
+	for i := 0; i < nu-m; i++ {
+		F[i][i+m] = 1
+	}
+	for i := nu; i < n-p; i++ {
+		F[i][i+p] = 1
+	}
+	for j := 0; j < m; j++ {
+		copy(F[nu-m+j], H[j])
+	}
+	G := zeros(n, p)
+	for c := 0; c < p; c++ {
+		G[n-p+c][c] = 1
+	}
+	return Problem{
+		Plant: OfflinePlant(),
+		F:     F, G: G, H: H, J: zeros(m, p),
+		Scales: spec.Scales{S: 0.0001, L: 1, R: 0.0002},
+		Margin: DefaultMargin,
+	}
+}
+
+// 제어기와 양자화/noise 조건
+type Problem struct {
+	Plant Plant
+
+	F, G, H, J [][]float64
+	Scales     spec.Scales
+	Noise      quant.Noise // σ (plaintext 정수 단위), 0 이면 양자화만
+
+	Margin float64
+}
+
+func DefaultProblem(sp *spec.Spec, pl Plant) Problem {
+	F, G, H, _, J := sp.Matrices()
+	return Problem{
+		Plant: pl,
+		F:     F, G: G, H: H, J: J,
+		Scales: sp.Scales,
+		Margin: DefaultMargin,
+	}
+}
+
+// 결과
+type Report struct {
+	// 폐루프 spectral radius (float 제어기 / 정수화 제어기)
+	RhoNominal   float64
+	RhoQuantized float64
+
+	// 매 스텝 교란 크기 (물리 단위, 성분별 최대)
+	DeltaY, DeltaX, DeltaU float64
+	// 교란 → u 의 ℓ1 이득 (성분 하나당)
+	GainY, GainX, GainU float64
+
+	UBound float64 // sup |Δu|
+	Margin float64
+}
+
+func (r Report) Stable() bool {
+	return r.RhoQuantized < 1
+}
+
+func (r Report) Within() bool {
+	return r.Stable() && r.UBound < r.Margin
+}
+
+func (r Report) String() string {
+	if !r.Stable() {
+		return fmt.Sprintf("closed loop unstable (ρ float=%.6f, quantized=%.6f): no steady-state bound", r.RhoNominal, r.RhoQuantized)
+	}
+	return fmt.Sprintf("ρ float=%.6f quantized=%.6f | δy=%.3g×%.3g + δx=%.3g×%.3g + δu=%.3g×%.3g → sup|Δu| ≤ %.4g (margin %.4g)",
+		r.RhoNominal, r.RhoQuantized, r.DeltaY, r.GainY, r.DeltaX, r.GainX, r.DeltaU, r.GainU, r.UBound, r.Margin)
+}
+
+// 닫힌 루프 행렬이 이 횟수 안에 수렴하지 않으면 불안정으로 판단
+const maxSteps = 200000
+
+// 오차 상한 계산
+func Analyze(pb Problem) (Report, error) {
+	pl := pb.Plant
+	n, m, p := len(pb.F), len(pb.H), len(pb.G[0])
+	switch {
+	case len(pl.B[0]) != m:
+		return Report{}, fmt.Errorf("plant has %d inputs but the controller has %d outputs (use Plant.Input)", len(pl.B[0]), m)
+	case len(pl.C) != p:
+		return Report{}, fmt.Errorf("plant has %d outputs but the controller has %d inputs", len(pl.C), p)
+	case len(pl.A) != len(pl.B) || len(pl.A[0]) != len(pl.C[0]):
+		return Report{}, errors.New("plant A, B, C dimensions do not match")
+	}
+
+	sc := pb.Scales
+	s, r, L := sc.S, sc.R, sc.L
+	rep := Report{Margin: pb.Margin}
+
+	// 정수화 후 다시 스케일을 곱한 제어기 (실제로 계산되는 값)
+	Gq := scale(s, trunc(1/s, pb.G))
+	Hq := scale(s, trunc(1/s, pb.H))
+	Jq := scale(s*s, trunc(1/(s*s), pb.J))
+
+	// 교란 크기 (NoiseSigmas·σ, slot 당)
+	k := float64(quant.NoiseSigmas)
+	ext := k * math.Sqrt(float64(n+p)) * pb.Noise.Ext
+	rep.DeltaY = r/2 + k*pb.Noise.Slot*r*L
+	rep.DeltaX = (ext + k*math.Sqrt(math.Max(pb.Noise.Slot*pb.Noise.Slot-pb.Noise.Fresh*pb.Noise.Fresh, 0))) * r * s * L
+	rep.DeltaU = ext * r * s * s * L
+
+	rep.RhoNominal = spectralRadius(closedLoop(pl, pb.F, pb.G, pb.H, pb.J))
+	Acl := closedLoop(pl, pb.F, Gq, Hq, Jq)
+	rep.RhoQuantized = spectralRadius(Acl)
+	if !rep.Stable() {
+		return rep, nil
+	}
+
+	// z = [x_p; x_c], 교란 입력별 B_w, D_w
+	np := len(pl.A)
+	Ccl := hcat(mul(Jq, pl.OutputMatrix()), Hq)
+	Bwy := vcat(mul(pl.B, Jq), Gq)
+	Bwx := vcat(zeros(np, n), eye(n))
+	Bwu := vcat(pl.B, zeros(n, m))
+
+	gain := func(Bw, Dw [][]float64) (float64, error) {
+		g, err := l1Gain(Acl, Bw, Ccl)
+		if err != nil {
+			return 0, err
+		}
+		return g + maxRowAbs(Dw), nil
+	}
+	var err error
+	if rep.GainY, err = gain(Bwy, Jq); err != nil {
+		return rep, err
+	}
+	if rep.GainX, err = gain(Bwx, zeros(m, n)); err != nil {
+		return rep, err
+	}
+	if rep.GainU, err = gain(Bwu, eye(m)); err != nil {
+		return rep, err
+	}
+	rep.UBound = rep.GainY*rep.DeltaY + rep.GainX*rep.DeltaX + rep.GainU*rep.DeltaU
+	return rep, nil
+}
+
+// A_cl = [[A + B J C_y, B H], [G C_y, F]],  C_y = OutputMatrix (오차 입력이면 -C)
+func closedLoop(pl Plant, F, G, H, J [][]float64) [][]float64 {
+	Cy := pl.OutputMatrix()
+	top := hcat(add(pl.A, mul(pl.B, mul(J, Cy))), mul(pl.B, H))
+	bot := hcat(mul(G, Cy), F)
+	return vcat(top, bot)
+}
+
+// ρ(A) ≈ ||A^k||^(1/k) (Gelfand), 정규화하면서 거듭제곱
+func spectralRadius(A [][]float64) float64 {
+	const steps = 4096
+	M := eye(len(A))
+	logNorm := 0.0
+	for k := 0; k < steps; k++ {
+		M = mul(M, A)
+		nrm := maxRowAbs(M)
+		if nrm == 0 {
+			return 0
+		}
+		M = scale(1/nrm, M)
+		logNorm += math.Log(nrm)
+	}
+	return math.Exp(logNorm / steps)
+}
+
+// Σ_k max_i Σ_j |(C A^k B)_ij|  (성분별 교란이 모두 같은 크기일 때의 최대 출력)
+func l1Gain(A, B, C [][]float64) (float64, error) {
+	// 행별로 따로 누적해야 ℓ∞ → ℓ∞ 유도 노름
+	rows := make([]float64, len(C))
+	X := B
+	for k := 0; k < maxSteps; k++ {
+		T := mul(C, X)
+		for i, row := range T {
+			for _, v := range row {
+				rows[i] += math.Abs(v)
+			}
+		}
+		if maxRowAbs(X) < 1e-14 {
+			g := 0.0
+			for _, v := range rows {
+				g = math.Max(g, v)
+			}
+			return g, nil
+		}
+		X = mul(A, X)
+	}
+	return 0, fmt.Errorf("impulse response did not decay within %d steps", maxSteps)
+}
+
+// ===== 작은 행렬 헬퍼 =====
+
+func mul(A, B [][]float64) [][]float64 {
+	out := zeros(len(A), len(B[0]))
+	for i := range A {
+		for k, a := range A[i] {
+			if a == 0 {
+				continue
+			}
+			for j, b := range B[k] {
+				out[i][j] += a * b
+			}
+		}
+	}
+	return out
+}
+
+func add(A, B [][]float64) [][]float64 {
+	out := zeros(len(A), len(A[0]))
+	for i := range A {
+		for j := range A[i] {
+			out[i][j] = A[i][j] + B[i][j]
+		}
+	}
+	return out
+}
+
+func scale(a float64, A [][]float64) [][]float64 {
+	out := zeros(len(A), len(A[0]))
+	for i := range A {
+		for j := range A[i] {
+			out[i][j] = a * A[i][j]
+		}
+	}
+	return out
+}
+
+// RGSW.EncPack 과 같이 0 방향 절삭
+func trunc(a float64, A [][]float64) [][]float64 {
+	out := scale(a, A)
+	for i := range out {
+		for j := range out[i] {
+			out[i][j] = math.Trunc(out[i][j])
+		}
+	}
+	return out
+}
+
+func zeros(r, c int) [][]float64 {
+	out := make([][]float64, r)
+	for i := range out {
+		out[i] = make([]float64, c)
+	}
+	return out
+}
+
+func eye(n int) [][]float64 {
+	out := zeros(n, n)
+	for i := range out {
+		out[i][i] = 1
+	}
+	return out
+}
+
+func hcat(A, B [][]float64) [][]float64 {
+	out := make([][]float64, len(A))
+	for i := range A {
+		out[i] = append(append([]float64(nil), A[i]...), B[i]...)
+	}
+	return out
+}
+
+func vcat(A, B [][]float64) [][]float64 {
+	return append(append([][]float64(nil), A...), B...)
+}
+
+func maxRowAbs(A [][]float64) float64 {
+	m := 0.0
+	for _, row := range A {
+		sum := 0.0
+		for _, v := range row {
+			sum += math.Abs(v)
+		}
+		m = math.Max(m, sum)
+	}
+	return m
+}
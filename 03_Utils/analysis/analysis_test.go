@@ -0,0 +1,37 @@
+package analysis
+
+import "testing"
+
+// offline_rlwe.go 의 MATLAB four-tank 설계는 y = C x_p 규약으로 안정하고 0.1901 안이어야 함
+func TestFourTankDesignStable(t *testing.T) {
+	rep, err := Analyze(FourTankProblem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.Within() {
+		t.Fatalf("shipped four-tank design: %v", rep)
+	}
+
+	// 부호를 뒤집으면 (오차 입력) 같은 설계가 불안정 → 규약이 결과를 바꾸는지 확인
+	pb := FourTankProblem()
+	pb.Plant.ErrorOutput = true
+	if rep, err := Analyze(pb); err != nil || rep.Stable() {
+		t.Fatalf("four-tank design with -C x_p input should be unstable: %v, %v", rep, err)
+	}
+}
+
+func TestOutputMatrixSign(t *testing.T) {
+	pl := OfflinePlant()
+	xp := []float64{1, 2, 3, 4}
+	y := pl.Output(xp)
+	pl.ErrorOutput = true
+	e := pl.Output(xp)
+	for i := range y {
+		if e[i] != -y[i] {
+			t.Fatalf("error output %v, want -%v", e, y)
+		}
+	}
+	if in, _ := pl.Input(0); !in.ErrorOutput {
+		t.Fatal("Input dropped ErrorOutput")
+	}
+}
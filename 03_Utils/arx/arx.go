@@ -0,0 +1,207 @@
+// conversion.m (05_achieve/conversion_RLWE.m) 의 Go 포팅
+//
+// 일반 이산시간 LTI 제어기
+//
+//	x(k+1) = A x(k) + B y(k)
+//	u(k)   = C x(k) + D y(k)
+//
+// 를 과거 입출력만 쓰는 ARX 형태로 변환
+//
+//	u(k) = Hu [u(k-n); ...; u(k-1)] + Hy [y(k-n); ...; y(k-1)] + D y(k)
+//
+// 상태가 과거 u, y 의 shift register 라 상태행렬은 0/1 (재암호화 없이 암호화 가능)
+// 초기 상태 x0 는 과거 입출력 열 Uini, Yini 로 바꿔서 같이 반환
+package arx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// 제어기 (A, B, C, D), 행렬은 [][]float64 (행 우선)
+type LTI struct {
+	A [][]float64 `json:"A"`
+	B [][]float64 `json:"B"`
+	C [][]float64 `json:"C"`
+	D [][]float64 `json:"D,omitempty"` // 없으면 0
+}
+
+// u(k) = Hu U + Hy Y + D y(k)
+// Hu: m × n·m, Hy: m × n·p (열 블록 i 가 u(k-n+i), y(k-n+i) 에 곱해짐)
+// Uini: n × m, Yini: n × p (행 i = u(-n+i), y(-n+i))
+type ARX struct {
+	N    int         `json:"n"`
+	Hu   [][]float64 `json:"hu"`
+	Hy   [][]float64 `json:"hy"`
+	D    [][]float64 `json:"D"`
+	Uini [][]float64 `json:"uIni"`
+	Yini [][]float64 `json:"yIni"`
+}
+
+// 상태 n, 출력(u) m, 입력(y) p
+func (sys *LTI) Dims() (n, m, p int) {
+	return len(sys.A), len(sys.C), len(sys.B[0])
+}
+
+func (sys *LTI) validate() error {
+	if len(sys.A) == 0 || len(sys.B) == 0 || len(sys.C) == 0 {
+		return errors.New("arx: A, B and C are required")
+	}
+	n, m, p := sys.Dims()
+	switch {
+	case !shape(sys.A, n, n):
+		return fmt.Errorf("arx: A must be %dx%d", n, n)
+	case !shape(sys.B, n, p):
+		return fmt.Errorf("arx: B must be %dx%d", n, p)
+	case !shape(sys.C, m, n):
+		return fmt.Errorf("arx: C must be %dx%d", m, n)
+	case sys.D != nil && !shape(sys.D, m, p):
+		return fmt.Errorf("arx: D must be %dx%d", m, p)
+	}
+	return nil
+}
+
+// LTI → ARX (x0: 제어기 초기 상태, nil 이면 0)
+//
+// conversion.m 과 같은 식
+//
+//	On = [C; CA; ...; CA^(n-1)],  Cn = [A^(n-1)B, ..., AB, B]
+//	Tn = 블록 하삼각 Toeplitz (대각 D, (j,i) 블록 C A^(j-i-1) B)
+//	Hu = C A^n pinv(On),  Hy = C (Cn - A^n pinv(On) Tn)
+//	Yini = Cn \ x0 (MATLAB \ 와 같은 basic solution),  Uini = Tn Yini
+//
+// (A, C) 가 관측 불가능해도 pinv 를 쓰므로 u 는 그대로 재현됨
+func Convert(sys *LTI, x0 []float64) (*ARX, error) {
+	if err := sys.validate(); err != nil {
+		return nil, err
+	}
+	n, m, p := sys.Dims()
+	D := sys.D
+	if D == nil {
+		D = zeros(m, p)
+	}
+	if x0 == nil {
+		x0 = make([]float64, n)
+	}
+	if len(x0) != n {
+		return nil, fmt.Errorf("arx: x0: want %d entries, got %d", n, len(x0))
+	}
+
+	// A^i, i = 0..n
+	pow := make([][][]float64, n+1)
+	pow[0] = eye(n)
+	for i := 1; i <= n; i++ {
+		pow[i] = mul(pow[i-1], sys.A)
+	}
+
+	// 관측 행렬 On (n·m × n)
+	On := [][]float64{}
+	for i := 0; i < n; i++ {
+		On = vcat(On, mul(sys.C, pow[i]))
+	}
+
+	// Toeplitz Tn (n·m × n·p)
+	Tn := zeros(n*m, n*p)
+	for j := 0; j < n; j++ {
+		for i := 0; i <= j; i++ {
+			blk := D
+			if i < j {
+				blk = mul(sys.C, mul(pow[j-i-1], sys.B))
+			}
+			setBlock(Tn, j*m, i*p, blk)
+		}
+	}
+
+	// (뒤집힌) 가제어 행렬 Cn (n × n·p)
+	Cn := zeros(n, n*p)
+	for i := 0; i < n; i++ {
+		setBlock(Cn, 0, i*p, mul(pow[n-1-i], sys.B))
+	}
+
+	OnPinv, err := pinv(On)
+	if err != nil {
+		return nil, err
+	}
+	CAn := mul(sys.C, pow[n])
+	Hu := mul(CAn, OnPinv)
+	Hy := mul(sys.C, sub(Cn, mul(pow[n], mul(OnPinv, Tn))))
+
+	// 초기 입출력 열: x(-n)=0 에서 시작해 x(0)=x0 가 되도록
+	yini := solveWide(Cn, x0)
+	if r := sub([][]float64{matVec(Cn, yini)}, [][]float64{x0}); maxAbs(r) > 1e-8*(1+maxAbs([][]float64{x0})) {
+		return nil, errors.New("arx: x0 is not reachable from past outputs ((A, B) not controllable)")
+	}
+	uini := matVec(Tn, yini)
+
+	return &ARX{
+		N:    n,
+		Hu:   Hu,
+		Hy:   Hy,
+		D:    D,
+		Uini: reshape(uini, n, m),
+		Yini: reshape(yini, n, p),
+	}, nil
+}
+
+// offline_rlwe.go 가 쓰는 벡터화 (conversion.m 의 vecHu', vecHy')
+// 행 i (과거 샘플 i) = 출력 j 마다 [Hu_i(j,:), 0...] 를 h = max(m, p) 칸씩 이어붙인 것
+func (a *ARX) Vectorized() (vecHu, vecHy [][]float64) {
+	m, p := len(a.Hu), len(a.Yini[0])
+	h := max(m, p)
+	vecHu = zeros(a.N, h*m)
+	vecHy = zeros(a.N, h*m)
+	for i := 0; i < a.N; i++ {
+		for j := 0; j < m; j++ {
+			copy(vecHu[i][h*j:], a.Hu[j][m*i:m*(i+1)])
+			copy(vecHy[i][h*j:], a.Hy[j][p*i:p*(i+1)])
+		}
+	}
+	return
+}
+
+// 평문 ARX 제어기 (변환 확인, 시뮬레이션용)
+type Runner struct {
+	arx  *ARX
+	U, Y [][]float64 // 최근 n 개 (오래된 것부터)
+}
+
+func (a *ARX) NewRunner() *Runner {
+	return &Runner{arx: a, U: clone(a.Uini), Y: clone(a.Yini)}
+}
+
+// u(k) 계산 후 과거 열을 한 칸씩 밀기
+func (r *Runner) Step(y []float64) []float64 {
+	a := r.arx
+	u := vecAdd(vecAdd(matVec(a.Hu, flatten(r.U)), matVec(a.Hy, flatten(r.Y))), matVec(a.D, y))
+	r.U = append(r.U[1:], u)
+	r.Y = append(r.Y[1:], append([]float64(nil), y...))
+	return u
+}
+
+// 같은 y 열 (고정 seed 정규분포) 로 LTI 와 ARX 를 steps 번 돌렸을 때 max |u_arx - u_lti|
+func (a *ARX) Verify(sys *LTI, x0 []float64, steps int) float64 {
+	n, m, p := sys.Dims()
+	D := sys.D
+	if D == nil {
+		D = zeros(m, p)
+	}
+	x := make([]float64, n)
+	copy(x, x0)
+	run := a.NewRunner()
+	rng := rand.New(rand.NewSource(1))
+	dev := 0.0
+	for k := 0; k < steps; k++ {
+		y := make([]float64, p)
+		for i := range y {
+			y[i] = rng.NormFloat64()
+		}
+		u := vecAdd(matVec(sys.C, x), matVec(D, y))
+		x = vecAdd(matVec(sys.A, x), matVec(sys.B, y))
+		for i, v := range run.Step(y) {
+			dev = math.Max(dev, math.Abs(v-u[i]))
+		}
+	}
+	return dev
+}
@@ -0,0 +1,219 @@
+package arx
+
+import (
+	"math"
+	"testing"
+)
+
+// offline_rlwe.go 의 plant (A, B, C)
+var (
+	plantA = [][]float64{
+		{0.998406460921939, 0, 0.00417376927758289, 0},
+		{0, 0.998893625478993, 0, -0.00332671872292611},
+		{0, 0, 0.995822899329324, 0},
+		{0, 0, 0, 0.996671438596397},
+	}
+	plantB = [][]float64{
+		{0.00831836513049678, 9.99686131895421e-06},
+		{-5.19664522845810e-06, 0.00627777465144397},
+		{0, 0.00477571210746992},
+		{0.00311667643652227, 0},
+	}
+	plantC = [][]float64{
+		{0.500000000000000, 0, 0, 0},
+		{0, 0.500000000000000, 0, 0},
+	}
+)
+
+// offline_rlwe.go 에 하드코딩된 conversion.m 결과 (vecHy', vecHu', Yini', Uini')
+var (
+	wantHy = [][]float64{
+		{0.334883269997112, -0.0993726952581632, 0.109105860257554, 0.340141173304891},
+		{0.340715074862138, -0.101693452659005, 0.111263681570879, 0.346096102431116},
+		{0.0212757993084255, -0.00721494759029773, 0.00717571762620109, 0.0215259945842975},
+		{-0.705323732730193, 0.209355413587286, -0.230615165512593, -0.715776671026420},
+	}
+	wantHu = [][]float64{
+		{-0.285602015399616, -0.000307101965816320, 0.00106747945670671, -0.286337872976116},
+		{0.183962668144521, -0.000156850543232820, 0.000585408816047406, 0.183342919294642},
+		{0.464731844320360, -0.000717550250832144, 0.000183250207538066, 0.464698956437188},
+		{0.631884279880355, -0.00124460838502882, -0.000477508261005455, 0.632382252336539},
+	}
+	wantYini = [][]float64{
+		{-168.915339084001, 152.553129120773},
+		{0, 0},
+		{0, 0},
+		{37.1009230518511, -33.8787596718866},
+	}
+	wantUini = [][]float64{
+		{0, 0},
+		{151.077820919228, -70.2395320362580},
+		{90.8566491021641, -42.4186053244263},
+		{54.6591007720606, -25.4768092703056},
+	}
+)
+
+func add(A, B [][]float64) [][]float64 {
+	out := clone(A)
+	for i := range out {
+		for j := range out[i] {
+			out[i][j] += B[i][j]
+		}
+	}
+	return out
+}
+
+// MATLAB idare 의 이득 K = (R + BᵀPB)⁻¹ BᵀPA (Riccati 반복)
+func dareGain(t *testing.T, A, B, Q, R [][]float64) [][]float64 {
+	t.Helper()
+	At, Bt := transpose(A), transpose(B)
+	P := clone(Q)
+	for it := 0; it < 100000; it++ {
+		Si, err := pinv(add(R, mul(Bt, mul(P, B))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		K := mul(Si, mul(Bt, mul(P, A)))
+		next := add(Q, sub(mul(At, mul(P, A)), mul(At, mul(P, mul(B, K)))))
+		if maxAbs(sub(next, P)) <= 1e-14*maxAbs(P) {
+			return K
+		}
+		P = next
+	}
+	t.Fatal("dare did not converge")
+	return nil
+}
+
+func closeTo(A, B [][]float64, tol float64) bool {
+	return shape(B, len(A), len(A[0])) && maxAbs(sub(A, B)) <= tol*(1+maxAbs(B))
+}
+
+// conversion.m 과 같은 설계 (Q = I, R = I 관측기 기반 제어기) → offline_rlwe.go 의 Hy, Hu, yy0, uu0
+func TestConvertMatchesConversionM(t *testing.T) {
+	K := dareGain(t, plantA, plantB, eye(4), eye(2))
+	for i := range K {
+		for j := range K[i] {
+			K[i][j] = -K[i][j]
+		}
+	}
+	L := transpose(dareGain(t, transpose(plantA), transpose(plantC), eye(4), eye(2)))
+	sys := &LTI{A: sub(add(plantA, mul(plantB, K)), mul(L, plantC)), B: L, C: K}
+
+	// xc0: x(-n) = 0 에서 yy0 를 넣어 간 상태, 그동안의 u 가 uu0
+	x := make([]float64, 4)
+	for i, y := range wantYini {
+		if u := matVec(sys.C, x); maxAbs([][]float64{sub([][]float64{u}, [][]float64{wantUini[i]})[0]}) > 1e-8*(1+maxAbs(wantUini)) {
+			t.Fatalf("uu0 row %d is not H x along yy0", i)
+		}
+		x = vecAdd(matVec(sys.A, x), matVec(sys.B, y))
+	}
+
+	a, err := Convert(sys, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hu, hy := a.Vectorized()
+	for _, c := range []struct {
+		name      string
+		got, want [][]float64
+	}{
+		{"Hy", hy, wantHy},
+		{"Hu", hu, wantHu},
+		{"Yini", a.Yini, wantYini},
+		{"Uini", a.Uini, wantUini},
+	} {
+		if !closeTo(c.got, c.want, 1e-9) {
+			t.Errorf("%s differs from conversion.m:\n got %v\nwant %v", c.name, c.got, c.want)
+		}
+	}
+	if dev := a.Verify(sys, x, 200); dev > 1e-8 {
+		t.Errorf("ARX deviates from LTI by %g", dev)
+	}
+}
+
+// 거의 Jordan 블록인 6 상태 제어기 (On 의 조건수 ~1e12)
+// MᵀM 고유값 분해 pinv 로는 50 스텝에 u 가 5e-3 어긋났음 (SVD 는 1e-6)
+// 중근이 1 근처라 ARX 재귀 자체가 반올림을 키움 → 정확한 Hu 로도 200 스텝이면 1e-3 이라 짧게 봄
+func TestConvertIllConditioned(t *testing.T) {
+	n := 6
+	A := zeros(n, n)
+	for i := range A {
+		A[i][i] = 0.999
+		if i+1 < n {
+			A[i][i+1] = 0.01
+		}
+	}
+	B := zeros(n, 1)
+	for i := range B {
+		B[i][0] = 1
+	}
+	C := zeros(1, n)
+	C[0][0] = 1
+	sys := &LTI{A: A, B: B, C: C, D: [][]float64{{0.5}}}
+
+	a, err := Convert(sys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev := a.Verify(sys, nil, 50); dev > 1e-5 {
+		t.Errorf("ARX deviates from LTI by %g over 50 steps", dev)
+	}
+}
+
+// pinv 가 Penrose 조건을 만족하는지 (rank 부족, 가로 / 세로)
+func TestPinvPenrose(t *testing.T) {
+	for _, M := range [][][]float64{
+		{{1, 2, 3}, {2, 4, 6}, {1, 0, 1}, {0, 1, 1}}, // rank 2, 세로
+		{{1, 2, 3, 4}, {2, 4, 6, 8}},                 // rank 1, 가로
+		{{1e-9, 0}, {0, 1}},
+	} {
+		P, err := pinv(M)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !closeTo(mul(M, mul(P, M)), M, 1e-12) || !closeTo(mul(P, mul(M, P)), P, 1e-12) {
+			t.Errorf("pinv(%v) = %v does not satisfy M P M = M, P M P = P", M, P)
+		}
+		MP, PM := mul(M, P), mul(P, M)
+		if !closeTo(MP, transpose(MP), 1e-12) || !closeTo(PM, transpose(PM), 1e-12) {
+			t.Errorf("pinv(%v): M P or P M is not symmetric", M)
+		}
+	}
+	// 1e-9 는 max(size)·eps·σmax 보다 크므로 0 으로 버리지 않음
+	P, _ := pinv([][]float64{{1e-9, 0}, {0, 1}})
+	if math.Abs(P[0][0]-1e9) > 1 {
+		t.Errorf("pinv dropped a singular value above the cutoff: %v", P)
+	}
+}
+
+// MATLAB \ 처럼 basic solution (0 이 아닌 원소가 rank 개)
+func TestSolveWideBasic(t *testing.T) {
+	M := [][]float64{{1, 0, 2, 0}, {0, 1, 0, 3}}
+	b := []float64{4, 6}
+	x := solveWide(M, b)
+	if r := vecAdd(matVec(M, x), []float64{-b[0], -b[1]}); math.Abs(r[0]) > 1e-12 || math.Abs(r[1]) > 1e-12 {
+		t.Fatalf("M x - b = %v", r)
+	}
+	// 노름이 큰 열 2, 3 만 씀
+	if x[0] != 0 || x[1] != 0 || math.Abs(x[2]-2) > 1e-12 || math.Abs(x[3]-2) > 1e-12 {
+		t.Fatalf("x = %v, want [0 0 2 2]", x)
+	}
+}
+
+// spec 의 PID realization (F = diag(1,0,1,0)): On 의 rank 가 2 라 pinv 가 작은 특이값을 버려야 함
+func TestConvertRankDeficient(t *testing.T) {
+	sys := &LTI{
+		A: [][]float64{{1, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 0}},
+		B: [][]float64{{1, 0}, {1, 0}, {0, 1}, {0, 1}},
+		C: [][]float64{{2.5, -42, 0.7, -7}},
+		D: [][]float64{{76.5, 37.7}},
+	}
+	x0 := []float64{0.1, 0, -0.2, 0}
+	a, err := Convert(sys, x0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev := a.Verify(sys, x0, 200); dev > 1e-9 {
+		t.Errorf("ARX deviates from LTI by %g", dev)
+	}
+}
@@ -0,0 +1,322 @@
+package arx
+
+import (
+	"errors"
+	"math"
+)
+
+// ===== 작은 행렬 헬퍼 =====
+
+const eps = 2.220446049250313e-16
+
+// Moore-Penrose 의사역행렬 (MATLAB pinv 와 같은 기준): pinv(M) = V Σ⁺ Uᵀ
+// M 자체의 특이값 분해 (one-sided Jacobi) 라 MᵀM 처럼 조건수가 제곱되지 않음
+// σ ≤ max(rows, cols)·eps·σmax 는 0 으로 취급
+func pinv(M [][]float64) ([][]float64, error) {
+	U, sig, V, err := svd(M)
+	if err != nil {
+		return nil, err
+	}
+	smax := 0.0
+	for _, v := range sig {
+		smax = math.Max(smax, v)
+	}
+	tol := float64(max(len(M), len(M[0]))) * eps * smax
+	out := zeros(len(M[0]), len(M))
+	for k, v := range sig {
+		if v <= tol {
+			continue
+		}
+		for i := range out {
+			if V[i][k] == 0 {
+				continue
+			}
+			w := V[i][k] / v
+			for j := range out[i] {
+				out[i][j] += w * U[j][k]
+			}
+		}
+	}
+	return out, nil
+}
+
+// 특이값 분해 M = U diag(σ) Vᵀ (one-sided Jacobi, Hestenes)
+// U: rows × k, V: cols × k, k = min(rows, cols), σ 는 정렬하지 않음
+// 열 쌍을 회전해서 서로 직교하게 만들면 열 노름이 특이값 (세로로 긴 행렬로 바꿔서)
+func svd(M [][]float64) (U [][]float64, sig []float64, V [][]float64, err error) {
+	if len(M) < len(M[0]) {
+		V, sig, U, err = svd(transpose(M))
+		return
+	}
+	rows, cols := len(M), len(M[0])
+	A := clone(M)
+	V = eye(cols)
+	tol := float64(rows) * eps
+	// 노름이 eps·‖M‖F 이하인 열은 0 (rank 부족이면 회전 뒤 반올림 잡음만 남아 방향이 수렴하지 않음, pinv 기준보다 작음)
+	small := 0.0
+	for _, row := range A {
+		for _, v := range row {
+			small += v * v
+		}
+	}
+	small *= eps * eps
+	for sweep := 0; sweep < 100; sweep++ {
+		rotated := false
+		for p := 0; p < cols; p++ {
+			for q := p + 1; q < cols; q++ {
+				alpha, beta, gamma := 0.0, 0.0, 0.0
+				for k := 0; k < rows; k++ {
+					alpha += A[k][p] * A[k][p]
+					beta += A[k][q] * A[k][q]
+					gamma += A[k][p] * A[k][q]
+				}
+				if alpha <= small || beta <= small || math.Abs(gamma) <= tol*math.Sqrt(alpha*beta) {
+					continue
+				}
+				rotated = true
+				zeta := (beta - alpha) / (2 * gamma)
+				t := math.Copysign(1, zeta) / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				c := 1 / math.Sqrt(1+t*t)
+				s := c * t
+				for k := 0; k < rows; k++ {
+					akp, akq := A[k][p], A[k][q]
+					A[k][p] = c*akp - s*akq
+					A[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < cols; k++ {
+					vkp, vkq := V[k][p], V[k][q]
+					V[k][p] = c*vkp - s*vkq
+					V[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+		if !rotated {
+			sig = make([]float64, cols)
+			for j := range sig {
+				for k := 0; k < rows; k++ {
+					sig[j] += A[k][j] * A[k][j]
+				}
+				sig[j] = math.Sqrt(sig[j])
+				if sig[j] == 0 {
+					continue
+				}
+				for k := 0; k < rows; k++ {
+					A[k][j] /= sig[j]
+				}
+			}
+			return A, sig, V, nil
+		}
+	}
+	return nil, nil, nil, errors.New("arx: SVD iteration did not converge")
+}
+
+// MATLAB 의 M \ b (행 ≤ 열): column pivoting QR 로 구한 basic solution (0 이 아닌 원소가 rank 개 이하)
+// 최소 노름 해 (pinv) 와는 다름 → conversion.m 의 Yini = Cn \ xc0 와 같은 값
+// |R(i,i)| ≤ max(rows, cols)·eps·|R(1,1)| 이면 rank 에서 뺌
+func solveWide(M [][]float64, b []float64) []float64 {
+	rows, cols := len(M), len(M[0])
+	A := clone(M)
+	y := append([]float64(nil), b...)
+	perm := make([]int, cols)
+	for j := range perm {
+		perm[j] = j
+	}
+	steps := min(rows, cols)
+	for k := 0; k < steps; k++ {
+		// 남은 행에서 노름이 가장 큰 열을 k 로
+		best, bestNorm := k, -1.0
+		for j := k; j < cols; j++ {
+			nrm := 0.0
+			for i := k; i < rows; i++ {
+				nrm += A[i][j] * A[i][j]
+			}
+			if nrm > bestNorm {
+				best, bestNorm = j, nrm
+			}
+		}
+		if best != k {
+			for i := range A {
+				A[i][k], A[i][best] = A[i][best], A[i][k]
+			}
+			perm[k], perm[best] = perm[best], perm[k]
+		}
+		// Householder: A[k:, k] → (α, 0, ..., 0)
+		nrm := math.Sqrt(bestNorm)
+		if nrm == 0 {
+			break
+		}
+		alpha := -math.Copysign(nrm, A[k][k])
+		v := make([]float64, rows-k)
+		for i := k; i < rows; i++ {
+			v[i-k] = A[i][k]
+		}
+		v[0] -= alpha
+		vv := 0.0
+		for _, x := range v {
+			vv += x * x
+		}
+		for j := k; j < cols; j++ {
+			d := 0.0
+			for i := k; i < rows; i++ {
+				d += v[i-k] * A[i][j]
+			}
+			d *= 2 / vv
+			for i := k; i < rows; i++ {
+				A[i][j] -= d * v[i-k]
+			}
+		}
+		d := 0.0
+		for i := k; i < rows; i++ {
+			d += v[i-k] * y[i]
+		}
+		d *= 2 / vv
+		for i := k; i < rows; i++ {
+			y[i] -= d * v[i-k]
+		}
+	}
+
+	r := 0
+	tol := float64(max(rows, cols)) * eps * math.Abs(A[0][0])
+	for r < steps && math.Abs(A[r][r]) > tol {
+		r++
+	}
+	z := make([]float64, r)
+	for i := r - 1; i >= 0; i-- {
+		sum := y[i]
+		for j := i + 1; j < r; j++ {
+			sum -= A[i][j] * z[j]
+		}
+		z[i] = sum / A[i][i]
+	}
+	x := make([]float64, cols)
+	for i, v := range z {
+		x[perm[i]] = v
+	}
+	return x
+}
+
+func mul(A, B [][]float64) [][]float64 {
+	out := zeros(len(A), len(B[0]))
+	for i := range A {
+		for k, a := range A[i] {
+			if a == 0 {
+				continue
+			}
+			for j, b := range B[k] {
+				out[i][j] += a * b
+			}
+		}
+	}
+	return out
+}
+
+func sub(A, B [][]float64) [][]float64 {
+	out := zeros(len(A), len(A[0]))
+	for i := range A {
+		for j := range A[i] {
+			out[i][j] = A[i][j] - B[i][j]
+		}
+	}
+	return out
+}
+
+func matVec(M [][]float64, v []float64) []float64 {
+	out := make([]float64, len(M))
+	for i, row := range M {
+		for j, a := range row {
+			out[i] += a * v[j]
+		}
+	}
+	return out
+}
+
+func vecAdd(a, b []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range a {
+		out[i] = a[i] + b[i]
+	}
+	return out
+}
+
+func transpose(M [][]float64) [][]float64 {
+	out := zeros(len(M[0]), len(M))
+	for i := range M {
+		for j := range M[i] {
+			out[j][i] = M[i][j]
+		}
+	}
+	return out
+}
+
+func zeros(r, c int) [][]float64 {
+	out := make([][]float64, r)
+	for i := range out {
+		out[i] = make([]float64, c)
+	}
+	return out
+}
+
+func eye(n int) [][]float64 {
+	out := zeros(n, n)
+	for i := range out {
+		out[i][i] = 1
+	}
+	return out
+}
+
+func clone(M [][]float64) [][]float64 {
+	out := make([][]float64, len(M))
+	for i := range M {
+		out[i] = append([]float64(nil), M[i]...)
+	}
+	return out
+}
+
+func vcat(A, B [][]float64) [][]float64 {
+	return append(append([][]float64(nil), A...), B...)
+}
+
+func setBlock(dst [][]float64, r, c int, blk [][]float64) {
+	for i := range blk {
+		copy(dst[r+i][c:], blk[i])
+	}
+}
+
+func shape(M [][]float64, r, c int) bool {
+	if len(M) != r {
+		return false
+	}
+	for _, row := range M {
+		if len(row) != c {
+			return false
+		}
+	}
+	return true
+}
+
+// [v(0); v(1); ...] (길이 rows·cols) → rows × cols
+func reshape(v []float64, rows, cols int) [][]float64 {
+	out := zeros(rows, cols)
+	for i := range out {
+		copy(out[i], v[i*cols:(i+1)*cols])
+	}
+	return out
+}
+
+func flatten(M [][]float64) []float64 {
+	out := []float64{}
+	for _, row := range M {
+		out = append(out, row...)
+	}
+	return out
+}
+
+func maxAbs(M [][]float64) float64 {
+	m := 0.0
+	for _, row := range M {
+		for _, v := range row {
+			m = math.Max(m, math.Abs(v))
+		}
+	}
+	return m
+}
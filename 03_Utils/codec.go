@@ -0,0 +1,182 @@
+package com_utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math"
+
+	"Encrypted_Cartpole/03_Utils/spec"
+
+	utils "github.com/CDSL-EncryptedControl/CDSL/utils"
+	RLWE "github.com/CDSL-EncryptedControl/CDSL/utils/core/RLWE"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+	"github.com/tuneinsight/lattigo/v6/utils/sampling"
+)
+
+// plant 쪽 암호화/복호화 (비밀키 + spec 스케일)
+//
+//	y → round(y/r) → EncPack (×1/L)
+//	u ← DecUnpack (×r s² L)
+//
+// ...Into 메서드는 codec 의 평문 / 다항식 버퍼를 재사용 (plant 루프에서 스텝마다 할당 없음, 한 goroutine 에서만)
+type PlantCodec struct {
+	Params rlwe.Parameters
+	Tau    int
+	M      int
+	Scales spec.Scales
+
+	ringQ *ring.Ring
+	q     uint64
+	sk    *rlwe.SecretKey
+	enc   *rlwe.Encryptor
+	dec   *rlwe.Decryptor
+
+	xe    ring.Sampler  // e (seed 압축 암호화)
+	exp   *seedExpander // a
+	seeds io.Reader     // a 의 seed (nil 이면 crypto/rand)
+	pt    *rlwe.Plaintext
+	ptDec *rlwe.Plaintext
+	a, e  ring.Poly
+}
+
+func NewPlantCodec(params rlwe.Parameters, tau, m int, sc spec.Scales, sk *rlwe.SecretKey) *PlantCodec {
+	ringQ := params.RingQ()
+	return &PlantCodec{
+		Params: params,
+		Tau:    tau,
+		M:      m,
+		Scales: sc,
+		ringQ:  ringQ,
+		q:      params.Q()[0],
+		sk:     sk,
+		enc:    rlwe.NewEncryptor(params, sk),
+		dec:    rlwe.NewDecryptor(params, sk),
+		exp:    newSeedExpander(ringQ),
+		pt:     rlwe.NewPlaintext(params, params.MaxLevel()),
+		ptDec:  rlwe.NewPlaintext(params, params.MaxLevel()),
+		a:      ringQ.NewPoly(),
+		e:      ringQ.NewPoly(),
+	}
+}
+
+// -unsafe-seed 결정적 모드: EncPack 의 encryptor, seed 압축 y 의 seed / e 를 u 에서 (u == nil 이면 그대로)
+// 전역 crypto/rand 는 건드리지 않음 (TLS 는 계속 무작위)
+func (pc *PlantCodec) UseUnsafeSeed(u *UnsafeSeed) error {
+	if u == nil {
+		return nil
+	}
+	xe, err := ring.NewSampler(u.PRNG("y-noise"), pc.ringQ, pc.Params.Xe(), false)
+	if err != nil {
+		return fmt.Errorf("unsafe seed: %w", err)
+	}
+	pc.enc = u.Encryptor(pc.Params, pc.sk)
+	pc.seeds = u.PRNG("y-seed")
+	pc.xe = xe
+	return nil
+}
+
+// plant bundle 로 codec 구성 (sk 잠금 해제 포함)
+func (b *Bundle) LoadPlantCodec(passphrase []byte) (*PlantCodec, error) {
+	sk, err := b.ReadSecretKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	mf := b.Manifest
+	return NewPlantCodec(mf.Params, mf.Tau, mf.M, mf.Scales, sk), nil
+}
+
+func (pc *PlantCodec) EncryptY(y []float64) *rlwe.Ciphertext {
+	yBar := utils.RoundVec(utils.ScalVecMult(1/pc.Scales.R, y))
+	return RLWE.EncPack(yBar, pc.Tau, 1/pc.Scales.L, *pc.enc, pc.ringQ, pc.Params)
+}
+
+// 전송용 seed 압축 y (controller 는 SeededCiphertext.Expand 로 복원)
+func (pc *PlantCodec) EncryptYSeeded(y []float64) (*SeededCiphertext, error) {
+	sc := pc.NewSeeded()
+	if err := pc.EncryptYSeededInto(y, sc); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// EncryptYSeededInto 에 계속 넘길 버퍼 (b 는 degree 0 암호문)
+func (pc *PlantCodec) NewSeeded() *SeededCiphertext {
+	return &SeededCiphertext{B: rlwe.NewCiphertext(pc.Params, 0, pc.Params.MaxLevel())}
+}
+
+// EncryptYSeeded 를 sc 에 (RLWE.EncPack 과 같은 평문, encryptZeroSk 와 같은 순서로 b = -a·s + e + m)
+func (pc *PlantCodec) EncryptYSeededInto(y []float64, sc *SeededCiphertext) error {
+	if len(y) > pc.Tau {
+		return fmt.Errorf("seeded ct: %d values for %d slots", len(y), pc.Tau)
+	}
+	seeds := pc.seeds
+	if seeds == nil {
+		seeds = rand.Reader
+	}
+	if _, err := io.ReadFull(seeds, sc.Seed[:]); err != nil {
+		return fmt.Errorf("seeded ct: %w", err)
+	}
+	if pc.xe == nil {
+		prng, err := sampling.NewPRNG()
+		if err != nil {
+			return fmt.Errorf("seeded ct: %w", err)
+		}
+		if pc.xe, err = ring.NewSampler(prng, pc.ringQ, pc.Params.Xe(), false); err != nil {
+			return fmt.Errorf("seeded ct: %w", err)
+		}
+	}
+	pc.encodeY(y)
+
+	b := sc.B.Value[0]
+	pc.exp.read(sc.Seed[:], pc.a)
+	pc.ringQ.MulCoeffsMontgomery(pc.a, pc.sk.Value.Q, b)
+	pc.ringQ.Neg(b, b)
+	pc.xe.Read(pc.e)
+	pc.ringQ.NTT(pc.e, pc.e)
+	pc.ringQ.Add(b, pc.e, b)
+	pc.ringQ.Add(b, pc.pt.Value, b)
+	*sc.B.MetaData = *pc.pt.MetaData
+	return nil
+}
+
+// RLWE.EncPack 의 평문: slot N·r/tau 에 round(y_r / r) · (1/L) mod q (NTT)
+func (pc *PlantCodec) encodeY(y []float64) {
+	q := pc.q
+	qf := float64(q)
+	scale := int64(1 / pc.Scales.L)
+	pt := pc.pt.Value
+	pt.Zero()
+	for r, v := range y {
+		x := scale * int64(math.Round(1/pc.Scales.R*v))
+		pt.Coeffs[0][pc.Params.N()*r/pc.Tau] = uint64(x - int64(math.Floor(float64(x)/qf))*int64(q))
+	}
+	pc.ringQ.NTT(pt, pt)
+}
+
+func (pc *PlantCodec) DecryptU(uCtPack *rlwe.Ciphertext) []float64 {
+	sc := pc.Scales
+	return RLWE.DecUnpack(uCtPack, pc.M, pc.Tau, *pc.dec, sc.R*sc.S*sc.S*sc.L, pc.ringQ, pc.Params)
+}
+
+// DecryptU 를 u (길이 M) 에 (RLWE.DecUnpack 과 같은 계산, 평문은 codec 버퍼)
+func (pc *PlantCodec) DecryptUInto(uCtPack *rlwe.Ciphertext, u []float64) {
+	sc := pc.Scales
+	scale := sc.R * sc.S * sc.S * sc.L
+	q := float64(pc.q)
+	offset := uint64(q / (scale * 2.0))
+
+	pc.ringQ.AddScalar(uCtPack.Value[0], offset, uCtPack.Value[0])
+	pt := pc.ptDec
+	pc.dec.Decrypt(uCtPack, pt)
+	if pt.IsNTT {
+		pc.ringQ.INTT(pt.Value, pt.Value)
+	}
+	pc.ringQ.SubScalar(uCtPack.Value[0], offset, uCtPack.Value[0])
+	for r := range u[:pc.M] {
+		val := float64(pt.Value.Coeffs[0][pc.Params.N()*r/pc.Tau])
+		val = val - math.Floor((val+q/2.0)/q)*q
+		u[r] = val * scale
+	}
+}
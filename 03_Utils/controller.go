@@ -0,0 +1,418 @@
+package com_utils
+
+import (
+	"fmt"
+	"math/bits"
+	"time"
+
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+// 암호화된 제어기 (controller bundle 만으로 동작, 비밀키 없음)
+//
+//	u = H x + J y,  x ← F x + G y   (x, y, u 는 tau-slot packed RLWE)
+//
+// bundle 에 ctFGslot 이 있으면 상태를 slot 별 암호문 Xs 로 유지 (x_k ← Σ [F | Ḡ]_kj ⊠ (x | y)_j)
+// → 매 스텝 unpack 은 y 만, X 는 nil
+//
+// 외부곱 / automorphism 은 Kernel 로, unpack 결과 / u / 다음 상태는 세션마다 미리 잡은 버퍼에 씀 (N, P, Tau 크기)
+// → 스텝마다 할당 없음. 대신 Unpack 의 slot 암호문은 다음 Unpack 까지, Output 의 u 는 다음 Output 까지만 유효
+type EncController struct {
+	Params rlwe.Parameters
+	N, P   int
+	Tau    int
+
+	F, G, H, J []*rgsw.Ciphertext
+	X          *rlwe.Ciphertext   // packed 상태 (slot pack 이 없을 때)
+	Xs         []*rlwe.Ciphertext // slot 별 상태 (SetSlotPack 뒤)
+
+	slots []SlotEntry        // FGs[i] 의 [F | Ḡ] 위치 (행 순서)
+	FGs   []*rgsw.Ciphertext // [F | Ḡ] 의 0 이 아닌 원소 (scalar RGSW)
+	xs0   []*rlwe.Ciphertext // x0 를 한 번 unpack 한 초기 상태
+
+	x0        *rlwe.Ciphertext // bundle 의 초기 상태 (Reset, NewSession)
+	ringQ     *ring.Ring
+	monomials []ring.Poly
+	kern      *Kernel
+
+	pool  *ExtPool  // nil 이면 한 코어에서 (MultPack 과 같은 순서)
+	terms []ExtTerm // 외부곱 항 (재사용)
+	ends  []int     // slot 갱신: 행 k 의 항은 terms[ends[k-1]:ends[k]]
+
+	// 스텝 버퍼 (alloc, 세션마다 따로)
+	unpX, unpY *unpacker        // unpX 는 packed 상태일 때만
+	xNext      *rlwe.Ciphertext // Update 가 쓰는 다음 X / Xs (끝나면 서로 바꿈)
+	next       []*rlwe.Ciphertext
+	u, tmp     *rlwe.Ciphertext
+
+	// UpdateAsync 의 goroutine (처음 호출할 때 띄우고 Close 에서 정리)
+	updReq  chan updateReq
+	updDone chan time.Duration
+}
+
+type updateReq struct {
+	xCt, yCt []*rlwe.Ciphertext
+}
+
+// bundle 의 xCtPack, ctF/G/H/J, rlk, gk_* 로 제어기 구성 (모두 manifest 해시 확인)
+func (b *Bundle) LoadController() (*EncController, error) {
+	mf := b.Manifest
+	params := mf.Params
+
+	x := new(rlwe.Ciphertext)
+	if err := b.ReadRT("xCtPack.dat", x); err != nil {
+		return nil, err
+	}
+	packs := map[string][]*rgsw.Ciphertext{}
+	for _, name := range []string{"ctF", "ctG", "ctH", "ctJ"} {
+		pk, err := b.LoadRGSWPack(name)
+		if err != nil {
+			return nil, err
+		}
+		packs[name] = pk
+	}
+	rlk := new(rlwe.RelinearizationKey)
+	if err := b.ReadRT("rlk.dat", rlk); err != nil {
+		return nil, err
+	}
+	gks, err := b.LoadGaloisKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewEncController(params, mf.N, mf.P, mf.Tau, packs["ctF"], packs["ctG"], packs["ctH"], packs["ctJ"], x, rlk, gks)
+
+	// 예전 bundle (ctFGslot 없음) 은 packed 상태 그대로
+	if _, ok := mf.Packs["ctFGslot"]; ok {
+		fgs, err := b.LoadRGSWPack("ctFGslot")
+		if err != nil {
+			return nil, err
+		}
+		if err := c.SetSlotPack(mf.SlotEntries, fgs); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// 메모리에 있는 pack / 키로 제어기 구성 (LoadController, 벤치마크용)
+func NewEncController(params rlwe.Parameters, n, p, tau int, F, G, H, J []*rgsw.Ciphertext, x *rlwe.Ciphertext, rlk *rlwe.RelinearizationKey, gks []*rlwe.GaloisKey) *EncController {
+	monomials, _ := PackSetup(params, tau)
+	c := &EncController{
+		Params: params,
+		N:      n,
+		P:      p,
+		Tau:    tau,
+		F:      F,
+		G:      G,
+		H:      H,
+		J:      J,
+
+		x0:        x.CopyNew(),
+		ringQ:     params.RingQ(),
+		monomials: monomials,
+		kern:      NewKernel(params, rlk, gks),
+	}
+	c.alloc()
+	return c
+}
+
+// 스텝 버퍼를 새로 잡고 상태는 초기 상태로 (slot 모드 여부가 정해진 뒤 다시 호출)
+func (c *EncController) alloc() {
+	c.terms = make([]ExtTerm, 0, max(c.N+c.P, len(c.FGs)))
+	c.ends = make([]int, c.N)
+	c.unpY = newUnpacker(c.Params, c.Tau, c.P)
+	c.u = rlwe.NewCiphertext(c.Params, 1)
+	c.tmp = rlwe.NewCiphertext(c.Params, 1)
+	if c.FGs == nil {
+		c.unpX = newUnpacker(c.Params, c.Tau, c.N)
+		c.X, c.xNext = c.x0.CopyNew(), rlwe.NewCiphertext(c.Params, 1)
+		c.Xs, c.next = nil, nil
+		return
+	}
+	c.unpX, c.X, c.xNext = nil, nil, nil
+	c.Xs = make([]*rlwe.Ciphertext, c.N)
+	c.next = make([]*rlwe.Ciphertext, c.N)
+	for i, ct := range c.xs0 {
+		c.Xs[i] = ct.CopyNew()
+		c.next[i] = rlwe.NewCiphertext(c.Params, 1)
+	}
+}
+
+// 상태를 slot 별로 유지 (EncSlotPack 의 결과), 초기 상태는 여기서 한 번만 unpack
+func (c *EncController) SetSlotPack(entries []SlotEntry, FGs []*rgsw.Ciphertext) error {
+	if len(entries) != len(FGs) {
+		return fmt.Errorf("slot pack: %d entries for %d ciphertexts", len(entries), len(FGs))
+	}
+	for i, e := range entries {
+		if e.Row < 0 || e.Row >= c.N || e.Col < 0 || e.Col >= c.N+c.P {
+			return fmt.Errorf("slot pack: entry %d (%d, %d) outside %dx%d", i, e.Row, e.Col, c.N, c.N+c.P)
+		}
+		if i > 0 && e.Row < entries[i-1].Row {
+			return fmt.Errorf("slot pack: entries are not in row order")
+		}
+	}
+	c.slots, c.FGs = entries, FGs
+	c.xs0 = newUnpacker(c.Params, c.Tau, c.N).unpack(c.kern, c.ringQ, c.monomials, c.x0)
+	c.alloc()
+	return nil
+}
+
+// 같은 bundle 로 독립된 세션 하나 (Kernel, 버퍼, 상태는 따로, 읽기만 하는 pack / 키는 공유)
+// 세션마다 다른 goroutine 에서 돌려도 됨
+func (c *EncController) NewSession() *EncController {
+	s := *c
+	s.kern = c.kern.ShallowCopy()
+	s.pool = nil
+	s.updReq, s.updDone = nil, nil
+	s.alloc()
+	switch {
+	case c.pool == nil:
+	case c.pool.own:
+		s.SetWorkers(c.pool.Workers())
+	default:
+		s.ShareWorkers(c.pool.run)
+	}
+	return &s
+}
+
+// Output / Update 의 외부곱을 자기 goroutine workers 개에 나눔 (1 이하 = 한 코어, 결과는 비트 단위로 같음)
+// NewSession 은 같은 worker 수로 자기 pool 을 만듦, 다 쓰면 Close
+func (c *EncController) SetWorkers(workers int) {
+	c.Close()
+	if workers > 1 {
+		c.pool = NewExtPool(c.kern, c.Params, workers)
+	}
+}
+
+// SetWorkers 와 같지만 여러 세션이 같이 쓰는 goroutine 에 (세션 수와 상관없이 외부곱 goroutine 은 w 의 개수)
+// NewSession 도 같은 w 를 씀, w 는 모든 세션이 Close 한 뒤 닫음
+func (c *EncController) ShareWorkers(w *ExtWorkers) {
+	c.Close()
+	if w != nil && w.Workers() > 1 {
+		c.pool = NewSharedExtPool(c.kern, c.Params, w)
+	}
+}
+
+// worker / UpdateAsync goroutine 정리 (UpdateAsync 의 결과를 받은 뒤에)
+func (c *EncController) Close() {
+	if c.pool != nil {
+		c.pool.Close()
+		c.pool = nil
+	}
+	if c.updReq != nil {
+		close(c.updReq)
+		c.updReq, c.updDone = nil, nil
+	}
+}
+
+// A a + B b 의 항 (MultPack(a, A) + MultPack(b, B) 와 같은 순서)
+func (c *EncController) terms2(a []*rlwe.Ciphertext, A []*rgsw.Ciphertext, b []*rlwe.Ciphertext, B []*rgsw.Ciphertext) []ExtTerm {
+	c.terms = c.terms[:0]
+	for i := range A {
+		c.terms = append(c.terms, ExtTerm{Ct: a[i], RGSW: A[i]})
+	}
+	for i := range B {
+		c.terms = append(c.terms, ExtTerm{Ct: b[i], RGSW: B[i]})
+	}
+	return c.terms
+}
+
+// out = Σ terms (pool 이 없으면 MultPack 과 같은 순서로 한 코어에서, out 은 terms 의 암호문과 겹치면 안 됨)
+func (c *EncController) extSumInto(out *rlwe.Ciphertext, terms []ExtTerm) {
+	if c.pool != nil {
+		c.pool.SumInto(out, terms)
+		return
+	}
+	out.Value[0].Zero()
+	out.Value[1].Zero()
+	for _, t := range terms {
+		c.kern.ExternalProduct(t.Ct, t.RGSW, c.tmp)
+		c.ringQ.Add(out.Value[0], c.tmp.Value[0], out.Value[0])
+		c.ringQ.Add(out.Value[1], c.tmp.Value[1], out.Value[1])
+	}
+}
+
+// outs[k] = Σ terms[ends[k-1]:ends[k]] (pool 이 있으면 모든 행을 한 번에 나눠서)
+func (c *EncController) extSumRowsInto(outs []*rlwe.Ciphertext, terms []ExtTerm, ends []int) {
+	if c.pool != nil {
+		c.pool.SumRowsInto(outs, terms, ends)
+		return
+	}
+	start := 0
+	for k, end := range ends {
+		c.extSumInto(outs[k], terms[start:end])
+		start = end
+	}
+}
+
+// 상태를 bundle 의 초기 상태로 (버퍼에 복사)
+func (c *EncController) Reset() {
+	if c.FGs == nil {
+		copyCt(c.X, c.x0)
+		return
+	}
+	for i, ct := range c.xs0 {
+		copyCt(c.Xs[i], ct)
+	}
+}
+
+func copyCt(dst, src *rlwe.Ciphertext) {
+	for i := range src.Value {
+		dst.Value[i].Copy(src.Value[i])
+	}
+	*dst.MetaData = *src.MetaData
+}
+
+// 로그용 상태 암호문 (packed, slot 모드면 첫 slot)
+// Update 는 다른 버퍼에 쓰고 바꾸므로 다음 Update 가 도는 동안에도 읽을 수 있음 (그다음 Update 전까지)
+func (c *EncController) State() *rlwe.Ciphertext {
+	if c.FGs == nil {
+		return c.X
+	}
+	return c.Xs[0]
+}
+
+// 상태와 y 를 slot 별 암호문으로 분리 (slot 모드면 x 는 Xs 그대로, unpack 은 y 만)
+// RLWE.UnpackCt 와 같은 값, 입력은 바꾸지 않음 (결과는 제어기 버퍼, 다음 Unpack 까지 유효)
+func (c *EncController) Unpack(yCtPack *rlwe.Ciphertext) (xCt, yCt []*rlwe.Ciphertext) {
+	if c.FGs != nil {
+		xCt = c.Xs
+	} else {
+		xCt = c.unpX.unpack(c.kern, c.ringQ, c.monomials, c.X)
+	}
+	yCt = c.unpY.unpack(c.kern, c.ringQ, c.monomials, yCtPack)
+	return
+}
+
+// u = H x + J y (packed, 제어기 버퍼라 다음 Output 전에 보내거나 복사)
+func (c *EncController) Output(xCt, yCt []*rlwe.Ciphertext) *rlwe.Ciphertext {
+	c.extSumInto(c.u, c.terms2(xCt, c.H, yCt, c.J))
+	return c.u
+}
+
+// x ← F x + G y
+func (c *EncController) Update(xCt, yCt []*rlwe.Ciphertext) {
+	if c.FGs != nil {
+		c.updateSlots(xCt, yCt)
+		return
+	}
+	c.extSumInto(c.xNext, c.terms2(xCt, c.F, yCt, c.G))
+	c.X, c.xNext = c.xNext, c.X
+}
+
+// slot 마다 x_k ← Σ [F | Ḡ]_kj ⊠ (x | y)_j (0 인 원소가 하나도 없는 행은 0 암호문)
+// 행마다 항이 1~2 개라 모든 행의 항을 한 번에 pool 로 (행마다 따로 나누면 worker 가 놀음)
+func (c *EncController) updateSlots(xCt, yCt []*rlwe.Ciphertext) {
+	c.terms = c.terms[:0]
+	i := 0
+	for k := range c.ends {
+		for ; i < len(c.slots) && c.slots[i].Row == k; i++ {
+			ct := yCt
+			col := c.slots[i].Col
+			if col < c.N {
+				ct = xCt
+			} else {
+				col -= c.N
+			}
+			c.terms = append(c.terms, ExtTerm{Ct: ct[col], RGSW: c.FGs[i]})
+		}
+		c.ends[k] = len(c.terms)
+	}
+	c.extSumRowsInto(c.next, c.terms, c.ends)
+	c.Xs, c.next = c.next, c.Xs
+}
+
+// Update 를 다른 goroutine 에서 (u 를 먼저 보내고 상태 업데이트를 plant 쪽 시간과 겹치기 위해)
+// 반환 채널에서 걸린 시간을 받기 전에는 이 제어기의 다른 메서드나 X / Xs 를 쓰면 안 됨
+// goroutine 과 채널은 계속 재사용 (매번 같은 채널을 돌려줌)
+func (c *EncController) UpdateAsync(xCt, yCt []*rlwe.Ciphertext) <-chan time.Duration {
+	if c.updReq == nil {
+		c.updReq = make(chan updateReq)
+		c.updDone = make(chan time.Duration, 1)
+		go c.updateLoop(c.updReq, c.updDone)
+	}
+	c.updReq <- updateReq{xCt, yCt}
+	return c.updDone
+}
+
+func (c *EncController) updateLoop(req <-chan updateReq, done chan<- time.Duration) {
+	for r := range req {
+		t := time.Now()
+		c.Update(r.xCt, r.yCt)
+		done <- time.Since(t)
+	}
+}
+
+// 한 스텝 (Unpack → Output → Update), u 반환 (다음 Step 까지 유효)
+func (c *EncController) Step(yCtPack *rlwe.Ciphertext) *rlwe.Ciphertext {
+	xCt, yCt := c.Unpack(yCtPack)
+	u := c.Output(xCt, yCt)
+	c.Update(xCt, yCt)
+	return u
+}
+
+// RLWE.UnpackCt 를 미리 잡은 tau 개 암호문으로 (입력은 스케일한 복사본부터 시작해서 그대로 둠)
+// 결과는 unpacker 버퍼 → 다음 unpack 까지 유효
+type unpacker struct {
+	scalar uint64             // q - (q+1)/tau
+	cts    []*rlwe.Ciphertext // tau 개
+	work   []*rlwe.Ciphertext // UnpackCt 의 ctUnpack (cts 를 bit reverse 순서로 바꿔 담음)
+	tmp    *rlwe.Ciphertext
+	n      int
+}
+
+func newUnpacker(params rlwe.Parameters, tau, n int) *unpacker {
+	q := params.Q()[0]
+	u := &unpacker{
+		scalar: q - (q+1)/uint64(tau),
+		work:   make([]*rlwe.Ciphertext, tau),
+		tmp:    rlwe.NewCiphertext(params, 1),
+		n:      n,
+	}
+	for range tau {
+		u.cts = append(u.cts, rlwe.NewCiphertext(params, 1))
+	}
+	return u
+}
+
+func (u *unpacker) unpack(k *Kernel, ringQ *ring.Ring, monomials []ring.Poly, in *rlwe.Ciphertext) []*rlwe.Ciphertext {
+	ct := u.work
+	copy(ct, u.cts)
+	ringQ.MulScalar(in.Value[0], u.scalar, ct[0].Value[0])
+	ringQ.MulScalar(in.Value[1], u.scalar, ct[0].Value[1])
+	*ct[0].MetaData = *in.MetaData
+
+	tau := len(ct)
+	for i := tau; i > 1; i /= 2 {
+		m := monomials[bits.Len(uint(i))-2] // log2(i) - 1
+		for j := 0; j < tau; j += i {
+			// UnpackCt 처럼 에러는 무시 (galois key 는 keygen 이 PackSetup 의 galEls 로 모두 만듦)
+			k.Automorphism(ct[j], uint64(i+1), u.tmp)
+			h := ct[i/2+j]
+			ringQ.Sub(u.tmp.Value[0], ct[j].Value[0], h.Value[0])
+			ringQ.Sub(u.tmp.Value[1], ct[j].Value[1], h.Value[1])
+			ringQ.Add(ct[j].Value[0], u.tmp.Value[0], ct[j].Value[0])
+			ringQ.Add(ct[j].Value[1], u.tmp.Value[1], ct[j].Value[1])
+			ringQ.MulCoeffsMontgomery(h.Value[0], m, h.Value[0])
+			ringQ.MulCoeffsMontgomery(h.Value[1], m, h.Value[1])
+		}
+	}
+
+	// bit reverse
+	j := 0
+	for i := 1; i < tau; i++ {
+		bit := tau >> 1
+		for j >= bit {
+			j -= bit
+			bit >>= 1
+		}
+		j += bit
+		if i < j {
+			ct[i], ct[j] = ct[j], ct[i]
+		}
+	}
+	return ct[:u.n]
+}
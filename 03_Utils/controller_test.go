@@ -0,0 +1,119 @@
+package com_utils
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"Encrypted_Cartpole/03_Utils/pid"
+	"Encrypted_Cartpole/03_Utils/spec"
+
+	utils "github.com/CDSL-EncryptedControl/CDSL/utils"
+	RGSW "github.com/CDSL-EncryptedControl/CDSL/utils/core/RGSW"
+	RLWE "github.com/CDSL-EncryptedControl/CDSL/utils/core/RLWE"
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// keygen.go 와 같은 스케일의 pack 으로 만든 제어기 (packed 상태) 와 plant codec, slot pack
+type testBundle struct {
+	sp      *spec.Spec
+	params  rlwe.Parameters
+	codec   *PlantCodec
+	ctrl    *EncController
+	entries []SlotEntry
+	FGs     []*rgsw.Ciphertext
+}
+
+func newTestBundle(t testing.TB, name string) *testBundle {
+	t.Helper()
+	sp, params := loadTestSpec(t, name)
+	tau := sp.Tau()
+	n, m, p := sp.Dims()
+	s, L, r := sp.Scales.S, sp.Scales.L, sp.Scales.R
+	levelQ, levelP := params.MaxLevelQ(), params.MaxLevelP()
+	ringQ := params.RingQ()
+	_, galEls := PackSetup(params, tau)
+	kgen := rlwe.NewKeyGenerator(params)
+	sk := kgen.GenSecretKeyNew()
+	rlk := kgen.GenRelinearizationKeyNew(sk)
+	gks := kgen.GenGaloisKeysNew(galEls, sk)
+	encRLWE := rlwe.NewEncryptor(params, sk)
+	encRGSW := rgsw.NewEncryptor(params, sk)
+
+	F, G, H, _, J := sp.Matrices()
+	GBar := utils.ScalMatMult(1/s, G)
+	ctF := RGSW.EncPack(F, tau, encRGSW, levelQ, levelP, ringQ, params)
+	ctG := RGSW.EncPack(GBar, tau, encRGSW, levelQ, levelP, ringQ, params)
+	ctH := RGSW.EncPack(utils.ScalMatMult(1/s, H), tau, encRGSW, levelQ, levelP, ringQ, params)
+	ctJ := RGSW.EncPack(utils.ScalMatMult(1/(s*s), J), tau, encRGSW, levelQ, levelP, ringQ, params)
+	entries, FGs := EncSlotPack(F, GBar, encRGSW, levelQ, levelP, params)
+	xBar := utils.RoundVec(utils.ScalVecMult(1/(r*s), sp.XIni))
+	xCt := RLWE.EncPack(xBar, tau, 1/L, *encRLWE, ringQ, params)
+
+	return &testBundle{
+		sp:      sp,
+		params:  params,
+		codec:   NewPlantCodec(params, tau, m, sp.Scales, sk),
+		ctrl:    NewEncController(params, n, p, tau, ctF, ctG, ctH, ctJ, xCt, rlk, gks),
+		entries: entries,
+		FGs:     FGs,
+	}
+}
+
+// keygen bundle 과 같은 slot 상태 세션
+func (tb *testBundle) slotSession(t testing.TB) *EncController {
+	t.Helper()
+	s := tb.ctrl.NewSession()
+	if err := s.SetSlotPack(tb.entries, tb.FGs); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// slot 상태 (한 코어 / worker) 와 packed 상태가 같은 y 열에서 평문 PID 와 같은 u 로 복호화되는지
+func TestSlotModeMatchesPacked(t *testing.T) {
+	tb := newTestBundle(t, "cartpole_N10.json")
+	n, m, p := tb.sp.Dims()
+	packed := tb.ctrl.NewSession()
+	slot := tb.slotSession(t)
+	slotPar := tb.slotSession(t)
+	slotPar.SetWorkers(3)
+	defer slotPar.Close()
+	plain, err := pid.NewController(tb.sp.StateSpace(), tb.sp.XIni)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 양자화 (r, s, L) 오차: 평문과의 차이는 N10 에서 0.1 안팎 → 0.3 까지, slot 과 packed 는 같은 양자화라 더 가까움
+	const tolPID, tolModes = 0.3, 0.1
+	rng := rand.New(rand.NewSource(2))
+	y := make([]float64, p)
+	uPID := make([]float64, m)
+	uPacked, uSlot, uPar := make([]float64, m), make([]float64, m), make([]float64, m)
+	worst := [2]float64{}
+	for k := 0; k < 60; k++ {
+		for i := range y {
+			y[i] = 2*rng.Float64() - 1
+		}
+		plain.StepInto(y, uPID)
+		yCt := tb.codec.EncryptY(y)
+		tb.codec.DecryptUInto(packed.Step(yCt), uPacked)
+		tb.codec.DecryptUInto(slot.Step(yCt), uSlot)
+		tb.codec.DecryptUInto(slotPar.Step(yCt), uPar)
+		for i := range uPID {
+			if uPar[i] != uSlot[i] {
+				t.Fatalf("step %d: slot mode with workers %g, without %g", k, uPar[i], uSlot[i])
+			}
+			worst[0] = math.Max(worst[0], math.Abs(uPacked[i]-uPID[i]))
+			worst[1] = math.Max(worst[1], math.Abs(uSlot[i]-uPID[i]))
+			if d := math.Abs(uSlot[i] - uPacked[i]); d > tolModes {
+				t.Fatalf("step %d: slot u %g, packed u %g", k, uSlot[i], uPacked[i])
+			}
+		}
+	}
+	if worst[0] > tolPID || worst[1] > tolPID {
+		t.Errorf("max |u - u_pid|: packed %.3f, slot %.3f (limit %.1f)", worst[0], worst[1], tolPID)
+	}
+	t.Logf("n=%d: max |u - u_pid| packed %.3f, slot %.3f", n, worst[0], worst[1])
+}
@@ -0,0 +1,219 @@
+package com_utils
+
+import (
+	"math/bits"
+
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+// 할당 없는 외부곱 / automorphism (제어 루프 hot path 용)
+//
+// lattigo 의 ExternalProduct / Automorphism 은 호출마다 Ring.AtLevel, ModDown 의 big.Int, ModuliChain 복사로
+// 20~30 번 (0.5~0.8 KB) 할당 → 스텝마다 수백 번이 쌓여 GC 가 loopIntervalMs 지터로 보임
+// spec 파라미터 (Q, P 모듈러스 하나씩) 에서 쓰는 경로만 lattigo 의 exported 연산으로 그대로 옮기고
+// 상수 (ModUp / ModDown 상수, P/2, automorphism index) 는 한 번만 계산 → 결과는 lattigo 와 비트 단위로 같음
+// 다른 모양의 파라미터 / 키면 lattigo evaluator 로 (할당은 그대로)
+// 버퍼를 쓰므로 goroutine 마다 ShallowCopy
+type Kernel struct {
+	params       rlwe.Parameters
+	ringQ, ringP *ring.Ring
+	fast         bool
+
+	evalRGSW *rgsw.Evaluator // fast 가 아닐 때
+	evalRLWE *rlwe.Evaluator
+
+	gks    map[uint64]*rlwe.GaloisKey // fast 경로로 쓸 수 있는 Galois key
+	autIdx map[uint64][]uint64
+
+	// ModDownQPtoQNTT 상수 (P 하나 → Q 하나)
+	q, p           uint64
+	bredQ, bredP   [2]uint64
+	mredQ, mredP   uint64
+	pInvQ          uint64 // q - P^-1 (Montgomery)
+	pHalfP, pHalfQ uint64 // floor(P/2) mod p, mod q
+	qiInv, qMont   uint64 // ModUpExact 의 (P/p)^-1 mod p, (P/p) mod q (Montgomery)
+	vq             [2]uint64
+	invNTT         ring.Poly
+	cw, cwNTT      []uint64
+	decQ, decP     []uint64
+	accQ, accP     [2]ring.Poly
+	tmp            [2]ring.Poly
+	buffQ, buffP   ring.Poly
+}
+
+// gks 는 Automorphism 에 쓸 Galois key (UnpackCt 용), rlk 는 fallback evaluator 에만
+func NewKernel(params rlwe.Parameters, rlk *rlwe.RelinearizationKey, gks []*rlwe.GaloisKey) *Kernel {
+	k := &Kernel{
+		params:   params,
+		ringQ:    params.RingQ(),
+		ringP:    params.RingP(),
+		evalRGSW: rgsw.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk)),
+		evalRLWE: rlwe.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk, gks...)),
+		gks:      map[uint64]*rlwe.GaloisKey{},
+		autIdx:   map[uint64][]uint64{},
+	}
+	k.fast = k.ringP != nil && k.ringQ.ModuliChainLength() == 1 && k.ringP.ModuliChainLength() == 1
+	if !k.fast {
+		return k
+	}
+
+	sQ, sP := k.ringQ.SubRings[0], k.ringP.SubRings[0]
+	k.q, k.p = sQ.Modulus, sP.Modulus
+	k.bredQ, k.bredP = sQ.BRedConstant, sP.BRedConstant
+	k.mredQ, k.mredP = sQ.MRedConstant, sP.MRedConstant
+
+	// genmodDownConstants / GenModUpConstants / ModUpPtoQ 와 같은 값
+	k.pInvQ = k.q - ring.MForm(ring.ModExp(k.p, k.q-2, k.q), k.q, k.bredQ)
+	k.pHalfP = k.p >> 1
+	k.pHalfQ = (k.p >> 1) % k.q
+	k.qiInv = ring.ModexpMontgomery(ring.MForm(1, k.p, k.bredP), int(k.p-2), k.p, k.mredP, k.bredP)
+	k.qMont = ring.MForm(1, k.q, k.bredQ)
+	k.vq[1] = ring.CRed(k.q-ring.MRed(1, ring.MForm(k.p, k.q, k.bredQ), k.q, k.mredQ), k.q)
+
+	for _, gk := range gks {
+		if gk.BaseTwoDecomposition != 0 || gk.LevelQ() != 0 || gk.LevelP() != 0 {
+			continue
+		}
+		idx, err := ring.AutomorphismNTTIndex(k.ringQ.N(), k.ringQ.NthRoot(), gk.GaloisElement)
+		if err != nil {
+			continue
+		}
+		k.gks[gk.GaloisElement] = gk
+		k.autIdx[gk.GaloisElement] = idx
+	}
+	k.alloc()
+	return k
+}
+
+func (k *Kernel) alloc() {
+	N := k.ringQ.N()
+	k.invNTT = k.ringQ.NewPoly()
+	k.cw, k.cwNTT = make([]uint64, N), make([]uint64, N)
+	k.decQ, k.decP = make([]uint64, N), make([]uint64, N)
+	for i := range k.accQ {
+		k.accQ[i] = k.ringQ.NewPoly()
+		k.accP[i] = k.ringP.NewPoly()
+		k.tmp[i] = k.ringQ.NewPoly()
+	}
+	k.buffQ, k.buffP = k.ringQ.NewPoly(), k.ringP.NewPoly()
+}
+
+// 키 / 상수는 공유, 버퍼와 fallback evaluator 는 새로
+func (k *Kernel) ShallowCopy() *Kernel {
+	c := *k
+	c.evalRGSW = k.evalRGSW.ShallowCopy()
+	c.evalRLWE = k.evalRLWE.ShallowCopy()
+	if c.fast {
+		c.alloc()
+	}
+	return &c
+}
+
+// rgsw.Evaluator.ExternalProduct 와 같음 (out 은 ct 와 같아도 됨, out 의 메타데이터는 그대로)
+func (k *Kernel) ExternalProduct(ct *rlwe.Ciphertext, g *rgsw.Ciphertext, out *rlwe.Ciphertext) {
+	if !k.fast || g.LevelQ() != 0 || g.LevelP() != 0 || ct.Level() != 0 || out.Level() != 0 {
+		k.evalRGSW.ExternalProduct(ct, g, out)
+		return
+	}
+	sQ, sP := k.ringQ.SubRings[0], k.ringP.SubRings[0]
+	pw2 := g.Value[0].BaseTwoDecomposition
+	mask := uint64((1 << pw2) - 1)
+	if mask == 0 {
+		mask = 0xFFFFFFFFFFFFFFFF
+	}
+	first := true
+	for i, el := range g.Value {
+		k.ringQ.INTT(ct.Value[i], k.invNTT)
+		for j := range el.Value[0] {
+			ring.MaskVec(k.invNTT.Coeffs[0], j*pw2, mask, k.cw)
+			v := el.Value[0][j]
+			sQ.NTTLazy(k.cw, k.cwNTT)
+			if first {
+				sQ.MulCoeffsMontgomery(v[0].Q.Coeffs[0], k.cwNTT, k.accQ[0].Coeffs[0])
+				sQ.MulCoeffsMontgomery(v[1].Q.Coeffs[0], k.cwNTT, k.accQ[1].Coeffs[0])
+			} else {
+				sQ.MulCoeffsMontgomeryThenAdd(v[0].Q.Coeffs[0], k.cwNTT, k.accQ[0].Coeffs[0])
+				sQ.MulCoeffsMontgomeryThenAdd(v[1].Q.Coeffs[0], k.cwNTT, k.accQ[1].Coeffs[0])
+			}
+			sP.NTTLazy(k.cw, k.cwNTT)
+			if first {
+				sP.MulCoeffsMontgomery(v[0].P.Coeffs[0], k.cwNTT, k.accP[0].Coeffs[0])
+				sP.MulCoeffsMontgomery(v[1].P.Coeffs[0], k.cwNTT, k.accP[1].Coeffs[0])
+			} else {
+				sP.MulCoeffsMontgomeryThenAdd(v[0].P.Coeffs[0], k.cwNTT, k.accP[0].Coeffs[0])
+				sP.MulCoeffsMontgomeryThenAdd(v[1].P.Coeffs[0], k.cwNTT, k.accP[1].Coeffs[0])
+			}
+			first = false
+		}
+	}
+	k.modDown(k.accQ[0], k.accP[0], out.Value[0])
+	k.modDown(k.accQ[1], k.accP[1], out.Value[1])
+}
+
+// rlwe.Evaluator.Automorphism 과 같음 (NTT 영역 degree 1 암호문, out 은 ct 와 같아도 됨)
+func (k *Kernel) Automorphism(ct *rlwe.Ciphertext, galEl uint64, out *rlwe.Ciphertext) error {
+	gk, ok := k.gks[galEl]
+	if !ok || galEl == 1 || !ct.IsNTT || ct.Degree() != 1 || ct.Level() != 0 || out.Level() != 0 {
+		return k.evalRLWE.Automorphism(ct, galEl, out)
+	}
+	sQ, sP := k.ringQ.SubRings[0], k.ringP.SubRings[0]
+
+	// GadgetProductLazy (P 하나, 2진 분해 없음 → DecomposeAndSplit 의 복사 + centering)
+	k.ringQ.INTT(ct.Value[1], k.invNTT)
+	q, p := k.q, k.p
+	for j, coeff := range k.invNTT.Coeffs[0] {
+		var pos, neg uint64 = 1, 0
+		if coeff >= q>>1 {
+			coeff = q - coeff
+			pos, neg = 0, 1
+		}
+		t := ring.BRedAdd(coeff, q, k.bredQ)
+		k.decQ[j] = t*pos + (q-t)*neg
+		t = ring.BRedAdd(coeff, p, k.bredP)
+		k.decP[j] = t*pos + (p-t)*neg
+	}
+	v := gk.Value[0][0]
+	sQ.NTTLazy(k.decQ, k.cwNTT)
+	sQ.MulCoeffsMontgomeryLazy(v[0].Q.Coeffs[0], k.cwNTT, k.accQ[0].Coeffs[0])
+	sQ.MulCoeffsMontgomeryLazy(v[1].Q.Coeffs[0], k.cwNTT, k.accQ[1].Coeffs[0])
+	sP.NTTLazy(k.decP, k.cwNTT)
+	sP.MulCoeffsMontgomeryLazy(v[0].P.Coeffs[0], k.cwNTT, k.accP[0].Coeffs[0])
+	sP.MulCoeffsMontgomeryLazy(v[1].P.Coeffs[0], k.cwNTT, k.accP[1].Coeffs[0])
+	for i := range k.accQ {
+		k.ringQ.Reduce(k.accQ[i], k.accQ[i])
+		k.ringP.Reduce(k.accP[i], k.accP[i])
+	}
+	k.modDown(k.accQ[0], k.accP[0], k.tmp[0])
+	k.modDown(k.accQ[1], k.accP[1], k.tmp[1])
+
+	k.ringQ.Add(k.tmp[0], ct.Value[0], k.tmp[0])
+	idx := k.autIdx[galEl]
+	k.ringQ.AutomorphismNTTWithIndex(k.tmp[0], idx, out.Value[0])
+	k.ringQ.AutomorphismNTTWithIndex(k.tmp[1], idx, out.Value[1])
+	*out.MetaData = *ct.MetaData
+	return nil
+}
+
+// BasisExtender.ModDownQPtoQNTT (P 하나 → Q 하나): out = P^-1 (pQ - ModUp(pP)) mod q
+func (k *Kernel) modDown(pQ, pP, out ring.Poly) {
+	sQ, sP := k.ringQ.SubRings[0], k.ringP.SubRings[0]
+	k.ringP.INTTLazy(pP, k.buffP)
+	bP, bQ := k.buffP.Coeffs[0], k.buffQ.Coeffs[0]
+
+	// ModUpPtoQ: +P/2, ModUpExact, -P/2 (모듈러스 하나라 CRT 재구성은 v = 0 인 곱 하나)
+	sP.AddScalar(bP, k.pHalfP, bP)
+	pf := float64(k.p)
+	for j, x := range bP {
+		y := ring.MRed(x, k.qiInv, k.p, k.mredP)
+		v := uint64(float64(y) / pf)
+		hi, lo := bits.Mul64(y, k.qMont)
+		h, _ := bits.Mul64(lo*k.mredQ, k.q)
+		bQ[j] = hi - h + k.q + k.vq[v]
+	}
+	sQ.SubScalar(bQ, k.pHalfQ, bQ)
+
+	sQ.NTTLazy(bQ, bQ)
+	sQ.SubThenMulScalarMontgomeryTwoModulus(bQ, pQ.Coeffs[0], k.pInvQ, out.Coeffs[0])
+}
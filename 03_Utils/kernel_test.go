@@ -0,0 +1,92 @@
+package com_utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// Kernel 의 외부곱 / automorphism 이 lattigo evaluator 와 비트 단위로 같은지
+// spec 파라미터 (Q, P 하나씩 → fast 경로) 와 모듈러스가 여럿인 파라미터 (lattigo fallback) 둘 다
+func TestKernelMatchesLattigo(t *testing.T) {
+	cases := []struct {
+		lit  rlwe.ParametersLiteral
+		fast bool
+	}{
+		{rlwe.ParametersLiteral{LogN: 10, LogQ: []int{56}, LogP: []int{51}, NTTFlag: true}, true}, // cartpole_N10
+		{rlwe.ParametersLiteral{LogN: 11, LogQ: []int{28}, LogP: []int{28}, NTTFlag: true}, true}, // cartpole_N11
+		{rlwe.ParametersLiteral{LogN: 12, LogQ: []int{40}, LogP: []int{40}, NTTFlag: true}, true}, // cartpole_test
+		{rlwe.ParametersLiteral{LogN: 10, LogQ: []int{40, 40}, LogP: []int{45}, NTTFlag: true}, false},
+		{rlwe.ParametersLiteral{LogN: 10, LogQ: []int{50}, LogP: []int{30, 30}, NTTFlag: true}, false},
+	}
+	for _, tc := range cases {
+		name := fmt.Sprintf("logN%d_Q%v_P%v", tc.lit.LogN, tc.lit.LogQ, tc.lit.LogP)
+		t.Run(name, func(t *testing.T) {
+			params, err := rlwe.NewParametersFromLiteral(tc.lit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkKernel(t, params, tc.fast)
+		})
+	}
+}
+
+func checkKernel(t *testing.T, params rlwe.Parameters, fast bool) {
+	_, galEls := PackSetup(params, 4)
+	kgen := rlwe.NewKeyGenerator(params)
+	sk := kgen.GenSecretKeyNew()
+	rlk := kgen.GenRelinearizationKeyNew(sk)
+	gks := kgen.GenGaloisKeysNew(galEls, sk)
+
+	k := NewKernel(params, rlk, gks)
+	if k.fast != fast {
+		t.Fatalf("fast path %v, want %v", k.fast, fast)
+	}
+	k = k.ShallowCopy() // 세션 / worker 가 쓰는 복사본
+	evalRGSW := rgsw.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk))
+	evalRLWE := rlwe.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk, gks...))
+
+	encRLWE := rlwe.NewEncryptor(params, sk)
+	encRGSW := rgsw.NewEncryptor(params, sk)
+	pt := rlwe.NewPlaintext(params, params.MaxLevel())
+	pt.Value.Coeffs[0][0] = 12345
+	params.RingQ().NTT(pt.Value, pt.Value)
+	ct, err := encRLWE.EncryptNew(pt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RGSW(X^3), 2진 분해 없음 / 있음
+	mono := rlwe.NewPlaintext(params, params.MaxLevel())
+	mono.Value.Coeffs[0][3] = 1
+	params.RingQ().NTT(mono.Value, mono.Value)
+	for _, pw2 := range []int{0, 10} {
+		g := rgsw.NewCiphertext(params, params.MaxLevelQ(), params.MaxLevelP(), pw2)
+		if err := encRGSW.Encrypt(mono, g); err != nil {
+			t.Fatal(err)
+		}
+		want := rlwe.NewCiphertext(params, 1, params.MaxLevel())
+		evalRGSW.ExternalProduct(ct, g, want)
+		got := rlwe.NewCiphertext(params, 1, params.MaxLevel())
+		k.ExternalProduct(ct, g, got)
+		if !sameValue(got, want) {
+			t.Errorf("ExternalProduct (base 2^%d) differs from lattigo", pw2)
+		}
+	}
+
+	for _, galEl := range galEls {
+		want := rlwe.NewCiphertext(params, 1, params.MaxLevel())
+		if err := evalRLWE.Automorphism(ct, galEl, want); err != nil {
+			t.Fatal(err)
+		}
+		got := rlwe.NewCiphertext(params, 1, params.MaxLevel())
+		if err := k.Automorphism(ct, galEl, got); err != nil {
+			t.Fatal(err)
+		}
+		if !sameValue(got, want) {
+			t.Errorf("Automorphism %d differs from lattigo", galEl)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package com_utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+// 비밀키 파일 형식 (sk.enc)
+//
+//	magic "CPSK" | version(1) | argon2id time(4) | memory KiB(4) | threads(1) | salt(16) | nonce(12) | AES-256-GCM(sk)
+//
+// 헤더 전체를 AAD 로 사용하므로 파라미터를 바꾸면 복호화가 실패함
+const (
+	sealedKeyMagic   = "CPSK"
+	sealedKeyVersion = 1
+
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB (64 MiB)
+	argonThreads = 4
+	argonKeyLen  = 32
+
+	// 읽을 때 허용하는 헤더 값 상한 (파일만 바꿔서 메모리 / 시간을 끝없이 쓰게 하는 것 방지)
+	maxArgonTime    = 16
+	maxArgonMemory  = 1024 * 1024 // KiB (1 GiB)
+	maxArgonThreads = 16
+
+	sealedSaltLen  = 16
+	sealedNonceLen = 12
+	sealedHeadLen  = 4 + 1 + 4 + 4 + 1 + sealedSaltLen + sealedNonceLen
+)
+
+// 비밀키 passphrase 를 넣는 환경변수
+const PassphraseEnv = "CARTPOLE_SK_PASSPHRASE"
+
+var ErrWrongPassphrase = errors.New("secret key: wrong passphrase or corrupted key file")
+
+// 비밀키를 passphrase 로 암호화해서 저장 (파일 권한 0600)
+func WriteSealedKey(path string, sk *rlwe.SecretKey, passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return errors.New("secret key: empty passphrase")
+	}
+	plain, err := sk.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	head := make([]byte, sealedHeadLen)
+	copy(head, sealedKeyMagic)
+	head[4] = sealedKeyVersion
+	binary.BigEndian.PutUint32(head[5:], argonTime)
+	binary.BigEndian.PutUint32(head[9:], argonMemory)
+	head[13] = argonThreads
+	salt := head[14 : 14+sealedSaltLen]
+	nonce := head[14+sealedSaltLen:]
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	aead, err := sealedKeyAEAD(passphrase, salt, argonTime, argonMemory, argonThreads)
+	if err != nil {
+		return err
+	}
+	out := aead.Seal(append([]byte(nil), head...), nonce, plain, head)
+
+	// 기존 파일이 있어도 0600 으로 맞춤
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Chmod(0o600); err != nil {
+		return err
+	}
+	if _, err := f.Write(out); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// 암호화된 비밀키 파일 읽기
+func ReadSealedKey(path string, passphrase []byte) (*rlwe.SecretKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < sealedHeadLen || string(b[:4]) != sealedKeyMagic {
+		return nil, fmt.Errorf("%s is not a sealed secret key file", path)
+	}
+	if b[4] != sealedKeyVersion {
+		return nil, fmt.Errorf("%s: unsupported key file version %d", path, b[4])
+	}
+	head := b[:sealedHeadLen]
+	tCost := binary.BigEndian.Uint32(head[5:])
+	memory := binary.BigEndian.Uint32(head[9:])
+	threads := head[13]
+	salt := head[14 : 14+sealedSaltLen]
+	nonce := head[14+sealedSaltLen:]
+	if tCost < 1 || tCost > maxArgonTime || memory < 8*uint32(threads) || memory > maxArgonMemory ||
+		threads < 1 || threads > maxArgonThreads {
+		return nil, fmt.Errorf("%s: argon2 parameters out of range (time %d, memory %d KiB, threads %d)", path, tCost, memory, threads)
+	}
+
+	aead, err := sealedKeyAEAD(passphrase, salt, tCost, memory, threads)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, b[sealedHeadLen:], head)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	sk := new(rlwe.SecretKey)
+	if err := sk.UnmarshalBinary(plain); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sk, nil
+}
+
+func sealedKeyAEAD(passphrase, salt []byte, tCost, memory uint32, threads uint8) (cipher.AEAD, error) {
+	key := argon2.IDKey(passphrase, salt, tCost, memory, threads, argonKeyLen)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// passphrase 얻기: 환경변수 → 없으면 터미널 프롬프트 (confirm 이면 두 번 입력)
+func Passphrase(prompt string, confirm bool) ([]byte, error) {
+	if env := os.Getenv(PassphraseEnv); env != "" {
+		return []byte(env), nil
+	}
+	pass, err := readPassphrase(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := readPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(pass, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return pass, nil
+}
+
+// 파이프 입력은 여러 번 읽어도 버퍼가 이어지도록 하나만 사용
+var stdinReader = bufio.NewReader(os.Stdin)
+
+func readPassphrase(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		pass, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return pass, err
+	}
+	// 파이프 입력 (스크립트)
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return nil, fmt.Errorf("read passphrase: %w", err)
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
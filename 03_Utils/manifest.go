@@ -0,0 +1,324 @@
+package com_utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"Encrypted_Cartpole/03_Utils/spec"
+
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// artifact 폴더에 keygen 이 같이 쓰는 manifest 파일 이름
+const ManifestName = "manifest.json"
+
+// 비밀키 파일 이름 (plant bundle 에만 존재해야 함, passphrase 로 암호화된 형식)
+const SecretKeyFile = "sk.enc"
+
+// 예전 keygen 이 쓰던 평문 비밀키
+const legacySecretKeyFile = "sk.dat"
+
+// bundle 종류
+// - plant     : sk + 스케일 (암호화/복호화)
+// - controller: RGSW pack, xCtPack, rlk, Galois key (평가용, 비밀키 없음)
+const (
+	RolePlant      = "plant"
+	RoleController = "controller"
+)
+
+// keygen 이 만든 artifact 묶음의 내용 (파라미터, 차원, 스케일, 파일 해시)
+type Manifest struct {
+	Role           string            `json:"role"`
+	Params         rlwe.Parameters   `json:"params"`
+	SpecSHA256     string            `json:"specSha256"`
+	N              int               `json:"n"`
+	M              int               `json:"m"`
+	P              int               `json:"p"`
+	Tau            int               `json:"tau"`
+	GaloisElements []uint64          `json:"galoisElements"`
+	Scales         spec.Scales       `json:"scales"`
+	Packs          map[string]int    `json:"packs"`                 // ctF → 4 (ctF_000 ~ ctF_003)
+	Files          map[string]string `json:"files"`                 // 파일 이름 → SHA-256
+	UnsafeSeed     bool              `json:"unsafeSeed,omitempty"`  // -unsafe-seed 로 만든 bundle (테스트 전용, UnsafeSeedWarning)
+	SlotEntries    []SlotEntry       `json:"slotEntries,omitempty"` // ctFGslot 각 암호문의 [F | Ḡ] 위치 (EncSlotPack)
+}
+
+// spec 과 파라미터로 manifest 뼈대 생성 (파일 해시는 HashFiles 로 채움)
+func NewManifest(role string, sp *spec.Spec, params rlwe.Parameters, galEls []uint64) *Manifest {
+	n, m, p := sp.Dims()
+	return &Manifest{
+		Role:           role,
+		Params:         params,
+		SpecSHA256:     sp.Fingerprint(),
+		N:              n,
+		M:              m,
+		P:              p,
+		Tau:            sp.Tau(),
+		GaloisElements: append([]uint64(nil), galEls...),
+		Scales:         sp.Scales,
+		Packs:          map[string]int{},
+		Files:          map[string]string{},
+	}
+}
+
+// 파일 SHA-256 (hex)
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dir 안의 파일들 해시를 manifest 에 기록
+func (mf *Manifest) HashFiles(dir string, names ...string) error {
+	for _, name := range names {
+		sum, err := FileSHA256(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("hash %s: %w", name, err)
+		}
+		mf.Files[name] = sum
+	}
+	return nil
+}
+
+// pack 파일 이름 목록 (ctF_000.dat ...)
+func PackFiles(name string, count int) []string {
+	out := make([]string, count)
+	for i := range out {
+		out[i] = fmt.Sprintf("%s_%03d.dat", name, i)
+	}
+	return out
+}
+
+// Galois key 파일 이름 (gk_<galEl>.dat)
+func GaloisKeyFile(galEl uint64) string {
+	return fmt.Sprintf("gk_%d.dat", galEl)
+}
+
+func WriteManifest(dir string, mf *Manifest) error {
+	b, err := json.MarshalIndent(mf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestName), append(b, '\n'), 0o644)
+}
+
+func LoadManifest(dir string) (*Manifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	mf := new(Manifest)
+	if err := json.Unmarshal(b, mf); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Join(dir, ManifestName), err)
+	}
+	if len(mf.Files) == 0 {
+		return nil, fmt.Errorf("%s lists no files", filepath.Join(dir, ManifestName))
+	}
+	return mf, nil
+}
+
+// 지금 spec 이 manifest 를 만든 spec 과 같은지 (파라미터, 차원, 스케일까지) 확인
+func (mf *Manifest) CheckSpec(sp *spec.Spec) error {
+	if mf.SpecSHA256 != sp.Fingerprint() {
+		return errors.New("manifest: spec fingerprint differs from the spec the artifacts were built from")
+	}
+	params, err := sp.RLWEParams()
+	if err != nil {
+		return err
+	}
+	if !mf.Params.Equal(&params) {
+		return errors.New("manifest: rlwe parameters differ from spec")
+	}
+	n, m, p := sp.Dims()
+	if mf.N != n || mf.M != m || mf.P != p || mf.Tau != sp.Tau() {
+		return fmt.Errorf("manifest: dims (n,m,p,tau)=(%d,%d,%d,%d), spec gives (%d,%d,%d,%d)",
+			mf.N, mf.M, mf.P, mf.Tau, n, m, p, sp.Tau())
+	}
+	if mf.Scales != sp.Scales {
+		return fmt.Errorf("manifest: scales %+v, spec gives %+v", mf.Scales, sp.Scales)
+	}
+	return nil
+}
+
+// manifest 로 검증하면서 읽는 artifact 폴더
+type Bundle struct {
+	Dir      string
+	Manifest *Manifest
+}
+
+func OpenBundle(dir string) (*Bundle, error) {
+	mf, err := LoadManifest(dir)
+	if err != nil {
+		return nil, fmt.Errorf("open bundle %s: %w", dir, err)
+	}
+	return &Bundle{Dir: dir, Manifest: mf}, nil
+}
+
+// plant bundle (비밀키 포함) 열기
+func OpenPlantBundle(dir string) (*Bundle, error) {
+	b, err := OpenBundle(dir)
+	if err != nil {
+		return nil, err
+	}
+	if b.Manifest.Role != RolePlant {
+		return nil, fmt.Errorf("%s is a %q bundle, want %q", dir, b.Manifest.Role, RolePlant)
+	}
+	if _, ok := b.Manifest.Files[SecretKeyFile]; !ok {
+		if _, legacy := b.Manifest.Files[legacySecretKeyFile]; legacy {
+			return nil, fmt.Errorf("plant bundle %s holds a plaintext %s; seal it first (go run sealkey.go -dir %s)",
+				dir, legacySecretKeyFile, dir)
+		}
+		return nil, fmt.Errorf("plant bundle %s has no %s", dir, SecretKeyFile)
+	}
+	return b, nil
+}
+
+// 검증 후 passphrase 로 비밀키 복호화
+func (b *Bundle) ReadSecretKey(passphrase []byte) (*rlwe.SecretKey, error) {
+	if err := b.Verify(SecretKeyFile); err != nil {
+		return nil, err
+	}
+	return ReadSealedKey(filepath.Join(b.Dir, SecretKeyFile), passphrase)
+}
+
+// controller bundle 열기
+// 보안 모델상 controller 는 비밀키를 보면 안 되므로 sk 가 있으면 거부
+func OpenControllerBundle(dir string) (*Bundle, error) {
+	b, err := OpenBundle(dir)
+	if err != nil {
+		return nil, err
+	}
+	if b.Manifest.Role != RoleController {
+		return nil, fmt.Errorf("%s is a %q bundle, want %q", dir, b.Manifest.Role, RoleController)
+	}
+	if err := b.RequireNoSecretKey(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// manifest 에 적혀 있든 없든 폴더에 비밀키 파일이 있으면 에러
+func (b *Bundle) RequireNoSecretKey() error {
+	for _, name := range []string{SecretKeyFile, legacySecretKeyFile} {
+		if _, ok := b.Manifest.Files[name]; ok {
+			return fmt.Errorf("refusing bundle %s: manifest lists a secret key (%s)", b.Dir, name)
+		}
+	}
+	matches, err := filepath.Glob(filepath.Join(b.Dir, "sk*"))
+	if err != nil {
+		return err
+	}
+	if len(matches) > 0 {
+		return fmt.Errorf("refusing bundle %s: secret key file present (%s)", b.Dir, filepath.Base(matches[0]))
+	}
+	return nil
+}
+
+// 파일 해시를 manifest 와 비교 (잘린 파일, 다른 keygen 결과 섞임 방지)
+func (b *Bundle) Verify(name string) error {
+	want, ok := b.Manifest.Files[name]
+	if !ok {
+		return fmt.Errorf("%s is not listed in %s", name, ManifestName)
+	}
+	got, err := FileSHA256(filepath.Join(b.Dir, name))
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("%s: sha256 mismatch (manifest %s, file %s)", name, shortHash(want), shortHash(got))
+	}
+	return nil
+}
+
+// 오류 메시지용 앞 12자 (manifest 값이 짧거나 깨져 있으면 그대로)
+func shortHash(h string) string {
+	if len(h) <= 12 {
+		return fmt.Sprintf("%q", h)
+	}
+	return h[:12] + "..."
+}
+
+// 모든 파일 검증
+func (b *Bundle) VerifyAll() error {
+	names := make([]string, 0, len(b.Manifest.Files))
+	for name := range b.Manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := b.Verify(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 검증 후 ReadRT
+func (b *Bundle) ReadRT(name string, obj io.ReaderFrom) error {
+	if err := b.Verify(name); err != nil {
+		return err
+	}
+	return ReadRT(filepath.Join(b.Dir, name), obj)
+}
+
+// manifest 에 적힌 개수만큼 검증 후 로드
+func (b *Bundle) LoadRGSWPack(name string) ([]*rgsw.Ciphertext, error) {
+	count, ok := b.Manifest.Packs[name]
+	if !ok || count == 0 {
+		return nil, fmt.Errorf("pack %s is not listed in %s", name, ManifestName)
+	}
+	out := make([]*rgsw.Ciphertext, count)
+	for i, fn := range PackFiles(name, count) {
+		out[i] = new(rgsw.Ciphertext)
+		if err := b.ReadRT(fn, out[i]); err != nil {
+			return nil, fmt.Errorf("load %s[%d] failed: %w", name, i, err)
+		}
+	}
+	return out, nil
+}
+
+// manifest 의 Galois element 순서대로 검증 후 로드
+func (b *Bundle) LoadGaloisKeys() ([]*rlwe.GaloisKey, error) {
+	if len(b.Manifest.GaloisElements) == 0 {
+		return nil, fmt.Errorf("no galois elements in %s", ManifestName)
+	}
+	out := make([]*rlwe.GaloisKey, len(b.Manifest.GaloisElements))
+	for i, galEl := range b.Manifest.GaloisElements {
+		fn := GaloisKeyFile(galEl)
+		gk := new(rlwe.GaloisKey)
+		if err := b.ReadRT(fn, gk); err != nil {
+			return nil, fmt.Errorf("load %s failed: %w", fn, err)
+		}
+		if gk.GaloisElement != galEl {
+			return nil, fmt.Errorf("%s holds galois element %d", fn, gk.GaloisElement)
+		}
+		out[i] = gk
+	}
+	return out, nil
+}
+
+// spec.json 사본 검증 + spec 비교 (다른 항목 이름까지 에러에 표시)
+func (b *Bundle) CheckSpec(sp *spec.Spec) error {
+	if err := b.Verify(spec.FileName); err != nil {
+		return err
+	}
+	if err := spec.CheckArtifacts(b.Dir, sp); err != nil {
+		return err
+	}
+	return b.Manifest.CheckSpec(sp)
+}
@@ -0,0 +1,304 @@
+// Prometheus text format (0.0.4) 로 내보내는 최소 counter / gauge / histogram (외부 의존성 없음)
+//
+//	reg := metrics.NewRegistry()
+//	phase := reg.Histogram("x_seconds", "help", metrics.LatencyBuckets, "session", "phase")
+//	phase.With("1", "recv").Observe(d.Seconds())
+//	http.Handle("/metrics", reg)
+//
+// 라벨 값 조합마다 series 하나, Delete 로 지우기 전까지 남음 (끝난 세션의 series 는 Delete("session", id))
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 제어 루프 단계 시간용 bucket (초, 샘플링 시간 30 ms 근처를 촘촘하게)
+var LatencyBuckets = []float64{0.0005, 0.001, 0.002, 0.005, 0.01, 0.015, 0.02, 0.025, 0.03, 0.04, 0.05, 0.1, 0.25, 1}
+
+type Registry struct {
+	mu   sync.Mutex
+	fams []*family
+}
+
+func NewRegistry() *Registry { return new(Registry) }
+
+type family struct {
+	name, help, typ string
+	labels          []string
+	buckets         []float64 // histogram 만
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// 라벨 값 하나 조합의 값 (counter / gauge 는 val, histogram 은 counts / sum / n)
+type series struct {
+	mu     sync.Mutex
+	values []string
+	val    float64
+	bounds []float64 // histogram bucket 상한
+	counts []uint64  // bucket 별 (누적 아님)
+	sum    float64
+	n      uint64
+}
+
+func (r *Registry) add(f *family) *family {
+	f.series = make(map[string]*series)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range r.fams {
+		if g.name == f.name {
+			panic("metrics: duplicate metric " + f.name)
+		}
+	}
+	r.fams = append(r.fams, f)
+	return f
+}
+
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.series[key]
+	if s == nil {
+		s = &series{values: append([]string(nil), values...)}
+		if f.buckets != nil {
+			s.bounds = f.buckets
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// 라벨 label 의 값이 value 인 series 를 모든 metric 에서 지움, 지운 개수 반환
+// 지운 뒤 With 하면 0 부터 새 series (지우기 전에 받아 둔 Counter 등은 더 이상 scrape 되지 않음)
+func (r *Registry) Delete(label, value string) int {
+	r.mu.Lock()
+	fams := append([]*family(nil), r.fams...)
+	r.mu.Unlock()
+
+	n := 0
+	for _, f := range fams {
+		i := -1
+		for j, l := range f.labels {
+			if l == label {
+				i = j
+			}
+		}
+		if i < 0 {
+			continue
+		}
+		f.mu.Lock()
+		for k, s := range f.series {
+			if s.values[i] == value {
+				delete(f.series, k)
+				n++
+			}
+		}
+		f.mu.Unlock()
+	}
+	return n
+}
+
+// 증가만 하는 값
+type CounterVec struct{ f *family }
+type Counter struct{ s *series }
+
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.add(&family{name: name, help: help, typ: "counter", labels: labels})}
+}
+
+// 라벨 값 순서는 등록할 때의 라벨 이름 순서
+func (v *CounterVec) With(values ...string) Counter { return Counter{v.f.with(values)} }
+
+func (c Counter) Inc() { c.Add(1) }
+
+func (c Counter) Add(d float64) {
+	if d < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.s.mu.Lock()
+	c.s.val += d
+	c.s.mu.Unlock()
+}
+
+// 오르내리는 값
+type GaugeVec struct{ f *family }
+type Gauge struct{ s *series }
+
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.add(&family{name: name, help: help, typ: "gauge", labels: labels})}
+}
+
+func (v *GaugeVec) With(values ...string) Gauge { return Gauge{v.f.with(values)} }
+
+func (g Gauge) Add(d float64) {
+	g.s.mu.Lock()
+	g.s.val += d
+	g.s.mu.Unlock()
+}
+
+func (g Gauge) Set(x float64) {
+	g.s.mu.Lock()
+	g.s.val = x
+	g.s.mu.Unlock()
+}
+
+// 분포 (bucket 은 오름차순 상한, +Inf 는 자동)
+type HistogramVec struct{ f *family }
+type Histogram struct{ s *series }
+
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: histogram buckets must be sorted")
+	}
+	return &HistogramVec{r.add(&family{name: name, help: help, typ: "histogram", labels: labels, buckets: buckets})}
+}
+
+func (v *HistogramVec) With(values ...string) Histogram {
+	return Histogram{v.f.with(values)}
+}
+
+func (h Histogram) Observe(x float64) {
+	i := sort.SearchFloat64s(h.s.bounds, x) // x ≤ 상한인 첫 bucket
+	h.s.mu.Lock()
+	if i < len(h.s.counts) {
+		h.s.counts[i]++
+	}
+	h.s.sum += x
+	h.s.n++
+	h.s.mu.Unlock()
+}
+
+// 등록 순서대로, 각 metric 안에서는 라벨 값 순서대로 text format 으로 씀
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	fams := append([]*family(nil), r.fams...)
+	r.mu.Unlock()
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	for _, f := range fams {
+		f.write(cw)
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// GET /metrics
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+func (f *family) write(w *countWriter) {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]*series, len(keys))
+	for i, k := range keys {
+		list[i] = f.series[k]
+	}
+	f.mu.Unlock()
+
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", f.name, escape(f.help, false), f.name, f.typ)
+	for _, s := range list {
+		s.mu.Lock()
+		if f.typ != "histogram" {
+			w.printf("%s%s %s\n", f.name, labelSet(f.labels, s.values, ""), formatFloat(s.val))
+			s.mu.Unlock()
+			continue
+		}
+		cum := uint64(0)
+		for i, le := range f.buckets {
+			cum += s.counts[i]
+			w.printf("%s_bucket%s %d\n", f.name, labelSet(f.labels, s.values, formatFloat(le)), cum)
+		}
+		w.printf("%s_bucket%s %d\n", f.name, labelSet(f.labels, s.values, "+Inf"), s.n)
+		w.printf("%s_sum%s %s\n", f.name, labelSet(f.labels, s.values, ""), formatFloat(s.sum))
+		w.printf("%s_count%s %d\n", f.name, labelSet(f.labels, s.values, ""), s.n)
+		s.mu.Unlock()
+	}
+}
+
+// {a="x",b="y"} (le 가 있으면 마지막에 붙임, 라벨이 없으면 빈 문자열)
+func labelSet(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escape(values[i], true))
+		b.WriteByte('"')
+	}
+	if le != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`le="`)
+		b.WriteString(le)
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// HELP 는 \\ 와 줄바꿈, 라벨 값은 " 까지
+func escape(s string, quote bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quote {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+func formatFloat(x float64) string {
+	switch {
+	case math.IsInf(x, 1):
+		return "+Inf"
+	case math.IsInf(x, -1):
+		return "-Inf"
+	case math.IsNaN(x):
+		return "NaN"
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+// 첫 에러 뒤로는 쓰지 않음
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) printf(format string, args ...any) {
+	if c.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDeleteSession(t *testing.T) {
+	reg := NewRegistry()
+	steps := reg.Counter("steps_total", "steps", "session", "params")
+	phase := reg.Histogram("phase_seconds", "phase", LatencyBuckets, "session", "phase")
+	total := reg.Counter("sessions_total", "sessions", "params")
+
+	steps.With("1", "N12").Inc()
+	steps.With("2", "N12").Inc()
+	phase.With("1", "recv").Observe(0.01)
+	total.With("N12").Inc()
+
+	if n := reg.Delete("session", "1"); n != 2 {
+		t.Fatalf("deleted %d series, want 2", n)
+	}
+	var b bytes.Buffer
+	reg.WriteTo(&b)
+	out := b.String()
+	if strings.Contains(out, `session="1"`) {
+		t.Fatalf("session 1 still exported:\n%s", out)
+	}
+	for _, want := range []string{`steps_total{session="2",params="N12"} 1`, `sessions_total{params="N12"} 1`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %s:\n%s", want, out)
+		}
+	}
+}
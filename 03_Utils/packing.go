@@ -0,0 +1,58 @@
+package com_utils
+
+import (
+	"math"
+
+	RGSW "github.com/CDSL-EncryptedControl/CDSL/utils/core/RGSW"
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+// RLWE pack/unpack 에 필요한 monomial 과 Galois element (tau slot 기준)
+// monomials 는 UnpackCt 에, galEls 는 Galois key 생성에 사용
+func PackSetup(params rlwe.Parameters, tau int) (monomials []ring.Poly, galEls []uint64) {
+	ringQ := params.RingQ()
+	logn := int(math.Log2(float64(tau)))
+
+	monomials = make([]ring.Poly, logn)
+	for i := 0; i < logn; i++ {
+		monomials[i] = ringQ.NewPoly()
+		idx := params.N() - params.N()/(1<<(i+1))
+		monomials[i].Coeffs[0][idx] = 1
+		ringQ.MForm(monomials[i], monomials[i])
+		ringQ.NTT(monomials[i], monomials[i])
+	}
+
+	galEls = make([]uint64, logn)
+	for i := 0; i < logn; i++ {
+		galEls[i] = uint64(tau/int(math.Pow(2, float64(i))) + 1)
+	}
+	return monomials, galEls
+}
+
+// slot 별 상태 갱신 x_Row ← Σ [F | Ḡ]_{Row,Col} (x | y)_Col 의 한 항 (Col < n 이면 x_Col, 아니면 y_{Col-n})
+type SlotEntry struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// [F | Ḡ] 의 0 이 아닌 원소를 하나씩 scalar RGSW 로 (행 순서, 0 인 원소는 외부곱도 생략)
+// 평문이 상수 다항식이라 UnpackCt 로 나온 slot 암호문과 외부곱하면 그 slot 에 원소를 곱한 slot 암호문이 됨
+// → 상태를 slot 별로 유지하면 매 스텝 x 를 unpack 할 필요가 없음 (0 의 위치는 bundle 의 spec 에서 이미 보이는 정보)
+func EncSlotPack(F, G [][]float64, encryptorRGSW *rgsw.Encryptor, levelQ, levelP int, params rlwe.Parameters) ([]SlotEntry, []*rgsw.Ciphertext) {
+	n := len(F)
+	var entries []SlotEntry
+	var cts []*rgsw.Ciphertext
+	for r := 0; r < n; r++ {
+		row := append(append([]float64(nil), F[r]...), G[r]...)
+		for c, v := range row {
+			if v == 0 {
+				continue
+			}
+			entries = append(entries, SlotEntry{Row: r, Col: c})
+			cts = append(cts, RGSW.Enc([][]float64{{v}}, encryptorRGSW, levelQ, levelP, params)[0][0])
+		}
+	}
+	return entries, cts
+}
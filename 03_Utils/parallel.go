@@ -0,0 +1,230 @@
+package com_utils
+
+import (
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+// 외부곱 한 항 (RLWE ⊠ RGSW)
+type ExtTerm struct {
+	Ct   *rlwe.Ciphertext
+	RGSW *rgsw.Ciphertext
+}
+
+// 외부곱 합 Σ Ct_i ⊠ RGSW_i 를 고정된 worker goroutine 들에 나눠 계산
+//
+// MultPack 은 pack 의 항을 한 코어에서 하나씩 외부곱 → 항들을 worker 수만큼 연속 구간으로 나누고
+// worker 별 부분합을 마지막에 더함. mod q 덧셈은 순서와 상관없이 같은 값이라 MultPack + RLWE.Add 와 비트 단위로 같음
+// 합이 여러 개면 (slot 상태의 행마다) 모든 행의 항을 이어서 한 번에 나누고, 구간이 행 경계에 걸리면 (행, 구간) job 으로 쪼갬
+// Kernel 은 goroutine 간 공유 불가 → job 마다 ShallowCopy 한 Kernel 과 tmp / 부분합 버퍼 (slot, 세션 것)
+//
+// goroutine 은 ExtWorkers 에 있고 여러 ExtPool (세션) 이 같이 씀 → 세션이 많아도 외부곱 goroutine 은 ExtWorkers 의 개수
+// worker 는 job 이 남은 세션을 돌아가며 하나씩 가져감 (round-robin) → 항이 많은 세션 (N12 등) 이 worker 를 다 잡고 있어도
+// 다른 세션은 지금 돌고 있는 job 하나 (extJobTerms 항 이하) 만 기다림
+type ExtPool struct {
+	params rlwe.Parameters
+	ringQ  *ring.Ring
+	run    *ExtWorkers
+	own    bool // NewExtPool: Close 때 run 도 닫음
+	kern   *Kernel
+	slots  []*extSlot
+	row    [1]int // SumInto 의 ends
+	out    [1]*rlwe.Ciphertext
+	wg     sync.WaitGroup
+
+	queue []extJob // 이번 합의 job (run.mu 아래에서 next 부터 가져감)
+	next  int
+}
+
+type extSlot struct {
+	kern     *Kernel
+	tmp, acc *rlwe.Ciphertext
+	row      int // acc 를 더할 합
+}
+
+// job 하나의 최대 항 수 (다른 세션이 기다리는 최대 시간 = 외부곱 이만큼)
+const extJobTerms = 2
+
+// 세션끼리 공유하는 외부곱 goroutine n 개 (파라미터가 다른 bundle 의 세션도 같이 씀)
+type ExtWorkers struct {
+	n      int
+	mu     sync.Mutex
+	cond   *sync.Cond
+	ready  []*ExtPool // job 이 남은 pool (앞에서 하나 가져가면 맨 뒤로)
+	closed bool
+}
+
+// slot 하나에 terms 구간 하나 (값으로 넘겨서 할당 없음)
+type extJob struct {
+	slot  *extSlot
+	terms []ExtTerm
+	ringQ *ring.Ring
+	wg    *sync.WaitGroup
+}
+
+// n 개의 goroutine 을 띄움, 다 쓰면 (모든 ExtPool 이 끝난 뒤) Close
+func NewExtWorkers(n int) *ExtWorkers {
+	w := &ExtWorkers{n: max(n, 1)}
+	w.cond = sync.NewCond(&w.mu)
+	for i := 0; i < w.n; i++ {
+		go w.run()
+	}
+	return w
+}
+
+func (w *ExtWorkers) Workers() int { return w.n }
+
+func (w *ExtWorkers) Close() {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+// p.queue 를 차례에 넣음 (p 는 job 이 다 끝날 때까지 queue 를 건드리지 않음)
+// 새로 온 세션은 아직 차례를 못 받았으니 맨 앞 → 돌고 있는 job 만 기다림
+func (w *ExtWorkers) submit(p *ExtPool) {
+	w.mu.Lock()
+	p.next = 0
+	w.ready = append(w.ready, nil)
+	copy(w.ready[1:], w.ready)
+	w.ready[0] = p
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+// 맨 앞 pool 의 다음 job (남은 job 이 있으면 그 pool 은 맨 뒤로), closed 이고 남은 게 없으면 false
+func (w *ExtWorkers) take() (extJob, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.ready) == 0 {
+		if w.closed {
+			return extJob{}, false
+		}
+		w.cond.Wait()
+	}
+	p := w.ready[0]
+	j := p.queue[p.next]
+	p.next++
+	copy(w.ready, w.ready[1:])
+	w.ready = w.ready[:len(w.ready)-1]
+	if p.next < len(p.queue) {
+		w.ready = append(w.ready, p)
+	}
+	return j, true
+}
+
+func (w *ExtWorkers) run() {
+	for {
+		j, ok := w.take()
+		if !ok {
+			return
+		}
+		s := j.slot
+		s.acc.Value[0].Zero()
+		s.acc.Value[1].Zero()
+		for _, t := range j.terms {
+			s.kern.ExternalProduct(t.Ct, t.RGSW, s.tmp)
+			j.ringQ.Add(s.acc.Value[0], s.tmp.Value[0], s.acc.Value[0])
+			j.ringQ.Add(s.acc.Value[1], s.tmp.Value[1], s.acc.Value[1])
+		}
+		j.wg.Done()
+	}
+}
+
+// 자기 goroutine workers 개를 가진 pool (k 는 복사만 하고 직접 쓰지 않음), 다 쓰면 Close
+func NewExtPool(k *Kernel, params rlwe.Parameters, workers int) *ExtPool {
+	p := NewSharedExtPool(k, params, NewExtWorkers(workers))
+	p.own = true
+	return p
+}
+
+// run 의 goroutine 을 쓰는 pool (slot 은 run 의 worker 수만큼, 행이 여러 개인 합에서 모자라면 처음 한 번 더 잡음)
+// Close 해도 run 은 그대로
+func NewSharedExtPool(k *Kernel, params rlwe.Parameters, run *ExtWorkers) *ExtPool {
+	p := &ExtPool{params: params, ringQ: params.RingQ(), run: run, kern: k}
+	for i := 0; i < run.Workers(); i++ {
+		p.slot(i)
+	}
+	return p
+}
+
+// i 번째 job 버퍼 (없으면 새로)
+func (p *ExtPool) slot(i int) *extSlot {
+	for len(p.slots) <= i {
+		p.slots = append(p.slots, &extSlot{
+			kern: p.kern.ShallowCopy(),
+			tmp:  rlwe.NewCiphertext(p.params, 1),
+			acc:  rlwe.NewCiphertext(p.params, 1),
+		})
+	}
+	return p.slots[i]
+}
+
+func (p *ExtPool) Workers() int { return p.run.Workers() }
+
+// 자기 goroutine 이면 종료 (이후 Sum 호출 금지)
+func (p *ExtPool) Close() {
+	if p.own {
+		p.run.Close()
+	}
+}
+
+// Σ terms 를 새 암호문으로 (한 번에 한 goroutine 만 호출)
+func (p *ExtPool) Sum(terms []ExtTerm) *rlwe.Ciphertext {
+	out := rlwe.NewCiphertext(p.params, 1)
+	p.SumInto(out, terms)
+	return out
+}
+
+// Sum 을 out 에 (out 을 0 으로 만들고 시작, terms 의 암호문과 겹치면 안 됨)
+// 다른 세션이 worker 를 쓰고 있으면 세션마다 돌아가며 job 하나씩
+func (p *ExtPool) SumInto(out *rlwe.Ciphertext, terms []ExtTerm) {
+	p.out[0], p.row[0] = out, len(terms)
+	p.SumRowsInto(p.out[:], terms, p.row[:])
+	p.out[0] = nil
+}
+
+// outs[r] = Σ terms[ends[r-1]:ends[r]] (ends[-1] = 0) 를 한 번에
+// 모든 행의 항을 worker 수 (구간이 extJobTerms 를 넘으면 더 많이) 로 고르게 나누고 (앞쪽 구간이 하나씩 더),
+// 구간을 행 경계에서 잘라 (행, 구간) job 으로 → 행마다 항이 1~2 개뿐인 slot 상태 갱신도 worker 를 다 씀
+func (p *ExtPool) SumRowsInto(outs []*rlwe.Ciphertext, terms []ExtTerm, ends []int) {
+	for _, out := range outs {
+		out.Value[0].Zero()
+		out.Value[1].Zero()
+	}
+	k := max(min(p.run.Workers(), len(terms)), (len(terms)+extJobTerms-1)/extJobTerms)
+	if k == 0 {
+		return
+	}
+	p.queue = p.queue[:0]
+	jobs := 0
+	for i, start, r := 0, 0, 0; i < k; i++ {
+		end := start + len(terms)/k
+		if i < len(terms)%k {
+			end++
+		}
+		for start < end {
+			for ends[r] <= start { // 빈 행 건너뜀
+				r++
+			}
+			stop := min(end, ends[r])
+			s := p.slot(jobs)
+			s.row = r
+			jobs++
+			p.queue = append(p.queue, extJob{slot: s, terms: terms[start:stop], ringQ: p.ringQ, wg: &p.wg})
+			start = stop
+		}
+	}
+	p.wg.Add(jobs)
+	p.run.submit(p)
+	p.wg.Wait()
+	for _, s := range p.slots[:jobs] {
+		out := outs[s.row]
+		p.ringQ.Add(out.Value[0], s.acc.Value[0], out.Value[0])
+		p.ringQ.Add(out.Value[1], s.acc.Value[1], out.Value[1])
+	}
+}
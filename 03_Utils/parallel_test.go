@@ -0,0 +1,229 @@
+package com_utils
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	utils "github.com/CDSL-EncryptedControl/CDSL/utils"
+	RGSW "github.com/CDSL-EncryptedControl/CDSL/utils/core/RGSW"
+	RLWE "github.com/CDSL-EncryptedControl/CDSL/utils/core/RLWE"
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+func sameValue(a, b *rlwe.Ciphertext) bool {
+	if len(a.Value) != len(b.Value) {
+		return false
+	}
+	for i := range a.Value {
+		if !a.Value[i].Equal(&b.Value[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ExtPool (자기 goroutine / 세션끼리 공유) 의 합이 MultPack 과 비트 단위로 같은지
+func TestExtPoolMatchesMultPack(t *testing.T) {
+	sp, params := loadTestSpec(t, "cartpole_N10.json")
+	tau := sp.Tau()
+	ringQ := params.RingQ()
+	monomials, galEls := PackSetup(params, tau)
+	kgen := rlwe.NewKeyGenerator(params)
+	sk := kgen.GenSecretKeyNew()
+	rlk := kgen.GenRelinearizationKeyNew(sk)
+	gks := kgen.GenGaloisKeysNew(galEls, sk)
+	encRLWE := rlwe.NewEncryptor(params, sk)
+
+	F, _, _, _, _ := sp.Matrices()
+	n := len(F)
+	ctF := RGSW.EncPack(F, tau, rgsw.NewEncryptor(params, sk), params.MaxLevelQ(), params.MaxLevelP(), ringQ, params)
+	xBar := utils.RoundVec(utils.ScalVecMult(1/(sp.Scales.R*sp.Scales.S), []float64{1, -2, 3, -4, 5, -6}[:n]))
+	xCt := RLWE.EncPack(xBar, tau, 1/sp.Scales.L, *encRLWE, ringQ, params)
+
+	evalRLWE := rlwe.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk, gks...))
+	evalRGSW := rgsw.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk))
+	xs := RLWE.UnpackCt(xCt, n, tau, evalRLWE, ringQ, monomials, params)
+	want := RGSW.MultPack(xs, ctF, evalRGSW, ringQ, params)
+
+	terms := make([]ExtTerm, n)
+	for i := range terms {
+		terms[i] = ExtTerm{Ct: xs[i], RGSW: ctF[i]}
+	}
+	kern := NewKernel(params, rlk, gks)
+
+	own := NewExtPool(kern, params, 3)
+	defer own.Close()
+	if got := own.Sum(terms); !sameValue(got, want) {
+		t.Fatal("ExtPool sum differs from MultPack")
+	}
+
+	// 세션 여러 개가 같은 goroutine 을 동시에
+	shared := NewExtWorkers(2)
+	defer shared.Close()
+	var wg sync.WaitGroup
+	errs := make([]bool, 4)
+	for s := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := NewSharedExtPool(kern, params, shared)
+			defer p.Close()
+			out := rlwe.NewCiphertext(params, 1)
+			for k := 0; k < 3; k++ {
+				p.SumInto(out, terms)
+				if !sameValue(out, want) {
+					errs[s] = true
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for s, bad := range errs {
+		if bad {
+			t.Errorf("session %d: shared ExtPool sum differs from MultPack", s)
+		}
+	}
+}
+
+// 여러 행의 합을 한 번에 나눠도 (구간이 행 경계에 걸리고, 빈 행이 있어도) 행마다 따로 더한 것과 같은지
+func TestExtPoolSumRows(t *testing.T) {
+	tb := newTestBundle(t, "cartpole_N10.json")
+	params := tb.params
+	xs, ys := tb.ctrl.NewSession().Unpack(tb.codec.EncryptY([]float64{0.3, -0.7}))
+	cts := append(append([]*rlwe.Ciphertext(nil), xs...), ys...)
+
+	// slot pack 처럼 행마다 1~2 개, 행 1 은 비어 있음
+	var terms []ExtTerm
+	ends := []int{}
+	for r, cnt := range []int{2, 0, 1, 2, 1} {
+		for j := 0; j < cnt; j++ {
+			terms = append(terms, ExtTerm{Ct: cts[(r+j)%len(cts)], RGSW: tb.FGs[(r+j)%len(tb.FGs)]})
+		}
+		ends = append(ends, len(terms))
+	}
+	kern := NewKernel(params, nil, nil)
+	want := make([]*rlwe.Ciphertext, len(ends))
+	tmp := rlwe.NewCiphertext(params, 1)
+	for r, start := 0, 0; r < len(ends); r++ {
+		want[r] = rlwe.NewCiphertext(params, 1)
+		for _, tm := range terms[start:ends[r]] {
+			kern.ExternalProduct(tm.Ct, tm.RGSW, tmp)
+			params.RingQ().Add(want[r].Value[0], tmp.Value[0], want[r].Value[0])
+			params.RingQ().Add(want[r].Value[1], tmp.Value[1], want[r].Value[1])
+		}
+		start = ends[r]
+	}
+
+	for _, w := range []int{1, 2, 4, 16} {
+		p := NewExtPool(kern, params, w)
+		outs := make([]*rlwe.Ciphertext, len(ends))
+		for r := range outs {
+			outs[r] = rlwe.NewCiphertext(params, 1)
+		}
+		for k := 0; k < 2; k++ { // 두 번째는 잡아 둔 slot 재사용
+			p.SumRowsInto(outs, terms, ends)
+			for r := range outs {
+				if !sameValue(outs[r], want[r]) {
+					t.Errorf("%d workers: row %d differs", w, r)
+				}
+			}
+		}
+		p.Close()
+	}
+}
+
+// 새로 온 세션의 job 이 바로 다음 차례, 그 뒤로는 세션마다 하나씩 돌아가며
+func TestExtWorkersTakeOrder(t *testing.T) {
+	w := &ExtWorkers{n: 1}
+	w.cond = sync.NewCond(&w.mu)
+	pool := func(jobs int) *ExtPool {
+		p := &ExtPool{}
+		for i := 0; i < jobs; i++ {
+			p.queue = append(p.queue, extJob{slot: &extSlot{row: i}, terms: make([]ExtTerm, 1)})
+		}
+		return p
+	}
+	long, a, b := pool(5), pool(2), pool(1)
+	w.submit(long)
+	order := []*ExtPool{}
+	next := func() {
+		j, _ := w.take()
+		for _, p := range []*ExtPool{long, a, b} {
+			for k := range p.queue {
+				if p.queue[k].slot == j.slot {
+					order = append(order, p)
+				}
+			}
+		}
+	}
+	next() // long 의 첫 job 이 도는 중
+	w.submit(a)
+	w.submit(b)
+	for len(w.ready) > 0 {
+		next()
+	}
+	want := []*ExtPool{long, b, a, long, a, long, long, long}
+	if len(order) != len(want) {
+		t.Fatalf("took %d jobs, want %d", len(order), len(want))
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("job %d from wrong session", i)
+		}
+	}
+}
+
+// worker 하나를 항이 많은 세션이 쓰고 있어도 짧은 세션이 먼저 끝나는지 (결과도 그대로)
+func TestExtWorkersFair(t *testing.T) {
+	tb := newTestBundle(t, "cartpole_N10.json")
+	params := tb.params
+	xs, _ := tb.ctrl.NewSession().Unpack(tb.codec.EncryptY([]float64{0.3, -0.7}))
+	kern := NewKernel(params, nil, nil)
+	long := make([]ExtTerm, 400)
+	for i := range long {
+		long[i] = ExtTerm{Ct: xs[i%len(xs)], RGSW: tb.FGs[i%len(tb.FGs)]}
+	}
+	short := long[:1]
+
+	run := NewExtWorkers(1)
+	defer run.Close()
+	pl := NewSharedExtPool(kern, params, run)
+	ps := NewSharedExtPool(kern, params, run)
+	defer pl.Close()
+	defer ps.Close()
+
+	want := rlwe.NewCiphertext(params, 1)
+	ps.SumInto(want, short)
+	wantL := rlwe.NewCiphertext(params, 1)
+	pl.SumInto(wantL, long)
+	outS := rlwe.NewCiphertext(params, 1)
+	outL := rlwe.NewCiphertext(params, 1)
+
+	taken := func() int { // 긴 세션이 가져간 job 수
+		run.mu.Lock()
+		defer run.mu.Unlock()
+		return pl.next
+	}
+	pl.next = 0 // 위의 wantL 에서 남은 값
+	done := make(chan struct{})
+	go func() {
+		pl.SumInto(outL, long)
+		close(done)
+	}()
+	for taken() == 0 {
+		runtime.Gosched()
+	}
+	ps.SumInto(outS, short)
+	after := taken()
+	<-done
+
+	if !sameValue(outS, want) || !sameValue(outL, wantL) {
+		t.Fatal("sum differs")
+	}
+	// 예전 (채널 하나) 에는 긴 합이 다 끝나야 짧은 합이 돌았음
+	if after == len(pl.queue) {
+		t.Errorf("short session waited for the whole long sum (%d jobs)", after)
+	}
+}
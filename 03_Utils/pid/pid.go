@@ -0,0 +1,171 @@
+// 병렬 PID → 이산시간 상태공간 realization
+//
+// 출력 y_i 마다 PID 채널 하나, 채널 출력의 합이 제어입력 u (스칼라)
+//
+//	u = Σ_i Kp y_i + Ki Ts Σ_{j≤k} y_i[j] + Kd/Ts · d_i[k]
+//	d_i[k] = a d_i[k-1] + (1-a)(y_i[k] - y_i[k-1]),  a = Tf/(Tf+Ts)  (Tf=0 → 순수 차분)
+//
+// 채널당 상태는 [적분(Σ_{j<k} y), 이전 y] (+ 필터가 있으면 [이전 d])
+// 필터가 없으면 F 가 0/1 대각이라 상태 재암호화가 필요 없음
+//
+// 결과 행렬은 [][]float64 (행 = 출력/상태) 로 utils.ScalMatMult, RGSW.EncPack 에 그대로 사용
+package pid
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PID 채널 하나 (연속시간 계수)
+// Ts=1 로 Build 하면 Ki, Kd 는 샘플 단위 계수로 그대로 쓰임
+type Channel struct {
+	Kp, Ki, Kd float64
+	// 미분 필터 시상수 (0 = 필터 없음)
+	Tf float64
+}
+
+// x[k+1] = F x[k] + G y[k],  u[k] = H x[k] + J y[k]
+type StateSpace struct {
+	F, G, H, J [][]float64
+}
+
+// 상태 n, 입력(=제어입력) m, 출력(=plant 출력) p 차원
+func (ss *StateSpace) Dims() (n, m, p int) {
+	return len(ss.F), len(ss.H), len(ss.G[0])
+}
+
+// 상태행렬이 0/1 정수인지 (재암호화 없이 암호 상태 갱신 가능)
+func (ss *StateSpace) IntegerF() bool {
+	for _, row := range ss.F {
+		for _, v := range row {
+			if v != 0 && v != 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// 채널들 → 상태공간 (샘플 시간 ts)
+func Build(ts float64, chs ...Channel) (*StateSpace, error) {
+	if ts <= 0 {
+		return nil, errors.New("pid: sample time must be positive")
+	}
+	if len(chs) == 0 {
+		return nil, errors.New("pid: at least one channel is required")
+	}
+
+	// 채널별 상태 수와 시작 위치
+	offs := make([]int, len(chs))
+	n := 0
+	for i, ch := range chs {
+		if ch.Tf < 0 {
+			return nil, fmt.Errorf("pid: channel %d: negative derivative filter time constant", i)
+		}
+		offs[i] = n
+		n += 2
+		if ch.Tf > 0 {
+			n++
+		}
+	}
+	p := len(chs)
+
+	ss := &StateSpace{
+		F: zeros(n, n),
+		G: zeros(n, p),
+		H: zeros(1, n),
+		J: zeros(1, p),
+	}
+	for i, ch := range chs {
+		o := offs[i]
+		ki := ch.Ki * ts
+		kd := ch.Kd / ts
+		a := 0.0
+		if ch.Tf > 0 {
+			a = ch.Tf / (ch.Tf + ts)
+		}
+
+		// 적분: x_I' = x_I + y
+		ss.F[o][o] = 1
+		ss.G[o][i] = 1
+		// 이전 출력: x_P' = y
+		ss.G[o+1][i] = 1
+
+		// u 의 채널 기여: Kp y + ki (x_I + y) + kd (a x_D + (1-a)(y - x_P))
+		ss.H[0][o] = ki
+		ss.H[0][o+1] = -kd * (1 - a)
+		ss.J[0][i] = ch.Kp + ki + kd*(1-a)
+
+		// 필터 상태: x_D' = a x_D + (1-a)(y - x_P)
+		if ch.Tf > 0 {
+			ss.F[o+2][o+1] = -(1 - a)
+			ss.F[o+2][o+2] = a
+			ss.G[o+2][i] = 1 - a
+			ss.H[0][o+2] = kd * a
+		}
+	}
+	return ss, nil
+}
+
+// 평문 제어기 (plant 의 shadow 계산, offline 시뮬레이션용)
+type Controller struct {
+	SS *StateSpace
+	X  []float64
+
+	next []float64 // StepInto 의 다음 상태 (X 와 번갈아 씀)
+}
+
+func NewController(ss *StateSpace, xIni []float64) (*Controller, error) {
+	n, _, _ := ss.Dims()
+	if len(xIni) != n {
+		return nil, fmt.Errorf("pid: initial state: want %d entries, got %d", n, len(xIni))
+	}
+	return &Controller{SS: ss, X: append([]float64(nil), xIni...)}, nil
+}
+
+// u = H x + J y 를 계산하고 상태를 x = F x + G y 로 갱신
+func (c *Controller) Step(y []float64) []float64 {
+	ss := c.SS
+	u := make([]float64, len(ss.H))
+	for i := range u {
+		u[i] = dot(ss.H[i], c.X) + dot(ss.J[i], y)
+	}
+	next := make([]float64, len(c.X))
+	for i := range next {
+		next[i] = dot(ss.F[i], c.X) + dot(ss.G[i], y)
+	}
+	c.X = next
+	return u
+}
+
+// Step 을 u (길이 m) 에, 상태는 버퍼 두 개를 번갈아 씀 (plant 루프용, 할당 없음)
+// 이전 스텝의 X 슬라이스를 들고 있으면 다음 StepInto 에서 덮어씀
+func (c *Controller) StepInto(y, u []float64) {
+	ss := c.SS
+	for i := range ss.H {
+		u[i] = dot(ss.H[i], c.X) + dot(ss.J[i], y)
+	}
+	if len(c.next) != len(c.X) {
+		c.next = make([]float64, len(c.X))
+	}
+	for i := range c.next {
+		c.next[i] = dot(ss.F[i], c.X) + dot(ss.G[i], y)
+	}
+	c.X, c.next = c.next, c.X
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func zeros(r, c int) [][]float64 {
+	out := make([][]float64, r)
+	for i := range out {
+		out[i] = make([]float64, c)
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package pid
+
+import (
+	"math"
+	"testing"
+)
+
+// 필터 없는 두 채널이면 예전에 offline_rgsw_N*.go 에 손으로 쓴 행렬과 같아야 함
+//
+//	F = diag(1,0,1,0), G = [1 0; 1 0; 0 1; 0 1]
+//	H = [Ki -Kd Li -Ld], J = [Kp+Ki+Kd  Lp+Li+Ld]
+func TestBuildMatchesHandWritten(t *testing.T) {
+	cases := []struct {
+		name                   string
+		kp, ki, kd, lp, li, ld float64
+	}{
+		{"cartpole_N10", 32, 2.7, 42, 30, 0.6, 7},
+		{"cartpole_N11", 32, 2.5, 40, 30, 0.1, 3},
+		{"cartpole_N12", 32, 2.5, 42, 30, 0.7, 7},
+		{"cartpole_test", 32, 2.5, 40, 30, 0.1, 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ss, err := Build(1, Channel{Kp: tc.kp, Ki: tc.ki, Kd: tc.kd}, Channel{Kp: tc.lp, Ki: tc.li, Kd: tc.ld})
+			if err != nil {
+				t.Fatal(err)
+			}
+			F := [][]float64{
+				{1, 0, 0, 0},
+				{0, 0, 0, 0},
+				{0, 0, 1, 0},
+				{0, 0, 0, 0},
+			}
+			G := [][]float64{
+				{1, 0},
+				{1, 0},
+				{0, 1},
+				{0, 1},
+			}
+			H := [][]float64{{tc.ki, -tc.kd, tc.li, -tc.ld}}
+			J := [][]float64{{tc.kp + tc.ki + tc.kd, tc.lp + tc.li + tc.ld}}
+			for _, m := range []struct {
+				name      string
+				got, want [][]float64
+			}{{"F", ss.F, F}, {"G", ss.G, G}, {"H", ss.H, H}, {"J", ss.J, J}} {
+				if !sameMat(m.got, m.want) {
+					t.Errorf("%s = %v, want %v", m.name, m.got, m.want)
+				}
+			}
+			if !ss.IntegerF() {
+				t.Error("F is not 0/1")
+			}
+		})
+	}
+}
+
+// Tf>0: 상태공간 출력이 차분방정식을 직접 돌린 것과 같은지
+//
+//	u = Kp y + Ki Ts Σ_{j≤k} y + Kd/Ts · d,  d[k] = a d[k-1] + (1-a)(y[k] - y[k-1])
+func TestFilteredDerivative(t *testing.T) {
+	const ts = 0.01
+	chs := []Channel{
+		{Kp: 32, Ki: 2.5, Kd: 42, Tf: 0.05},
+		{Kp: 30, Ki: 0.7, Kd: 7}, // 필터 없는 채널과 섞어서
+	}
+	ss, err := Build(ts, chs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, m, p := ss.Dims(); n != 5 || m != 1 || p != 2 {
+		t.Fatalf("dims (%d,%d,%d), want (5,1,2)", n, m, p)
+	}
+	if ss.IntegerF() {
+		t.Error("filtered F reported as 0/1")
+	}
+	c, err := NewController(ss, make([]float64, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := make([]float64, len(chs))
+	prevY := make([]float64, len(chs))
+	d := make([]float64, len(chs))
+	for k := 0; k < 200; k++ {
+		y := []float64{math.Sin(0.1 * float64(k)), 0.5*math.Cos(0.07*float64(k)) + 0.01*float64(k%7)}
+		want := 0.0
+		for i, ch := range chs {
+			a := 0.0
+			if ch.Tf > 0 {
+				a = ch.Tf / (ch.Tf + ts)
+			}
+			sum[i] += y[i]
+			d[i] = a*d[i] + (1-a)*(y[i]-prevY[i])
+			prevY[i] = y[i]
+			want += ch.Kp*y[i] + ch.Ki*ts*sum[i] + ch.Kd/ts*d[i]
+		}
+		got := c.Step(y)[0]
+		if math.Abs(got-want) > 1e-9*math.Max(1, math.Abs(want)) {
+			t.Fatalf("step %d: u = %v, want %v", k, got, want)
+		}
+	}
+}
+
+// StepInto 는 Step 과 같은 값
+func TestStepInto(t *testing.T) {
+	ss, err := Build(0.02, Channel{Kp: 1, Ki: 2, Kd: 3, Tf: 0.1}, Channel{Kp: 4, Ki: 5, Kd: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := NewController(ss, make([]float64, 5))
+	b, _ := NewController(ss, make([]float64, 5))
+	u := make([]float64, 1)
+	for k := 0; k < 20; k++ {
+		y := []float64{float64(k%3) - 1, 0.1 * float64(k)}
+		want := a.Step(y)
+		b.StepInto(y, u)
+		if u[0] != want[0] {
+			t.Fatalf("step %d: StepInto %v, Step %v", k, u[0], want[0])
+		}
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	if _, err := Build(0, Channel{}); err == nil {
+		t.Error("ts=0 accepted")
+	}
+	if _, err := Build(1); err == nil {
+		t.Error("no channels accepted")
+	}
+	if _, err := Build(1, Channel{Tf: -1}); err == nil {
+		t.Error("negative Tf accepted")
+	}
+	ss, _ := Build(1, Channel{})
+	if _, err := NewController(ss, []float64{0}); err == nil {
+		t.Error("short xIni accepted")
+	}
+}
+
+func sameMat(a, b [][]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
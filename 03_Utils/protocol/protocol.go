@@ -0,0 +1,403 @@
+// plant ↔ controller 메시지 프레이밍 (TCP 스트림 위)
+//
+//	magic "CPEC"(4) | version(1) | type(1) | seq(8) | length(4) | payload(length) | CRC32(4)
+//
+// 정수는 big endian, CRC32 (IEEE) 는 magic 부터 payload 끝까지
+// seq 는 plant 의 반복 번호 (controller 는 Y 의 seq 를 U 에 그대로 붙여서 응답)
+// magic 이 안 맞으면 다음 magic 까지 건너뛰어서 다시 맞추고, CRC 가 틀린 프레임은 ErrChecksum 으로 버림
+// length 는 타입별 상한 (SetMaxPayload, 기본 Y / U 는 MaxPayload, 나머지는 MaxControlPayload) 을 넘으면 ErrTooLarge
+// payload 버퍼는 header 의 length 만큼 한 번에 잡지 않고 실제로 들어온 만큼 늘림 (length 만 큰 header 로 메모리를 못 잡게)
+//
+// net.Conn 이면 ReadTimeout / WriteTimeout 으로 deadline 을 걸 수 있음
+// 읽기 deadline 에 걸리면 읽던 프레임은 Conn 에 남겨두고 다음 Recv 에서 이어 읽음 (스트림이 어긋나지 않음)
+// 쓰기 deadline 에 걸렸을 때 한 바이트도 안 나갔으면 IsTimeout (프레임을 건너뛰고 계속 쓸 수 있음)
+// 일부라도 나갔으면 받는 쪽은 남은 length 만큼 다음 프레임을 payload 로 읽어서 스트림이 어긋나므로
+// 연결을 닫고 ErrBroken (timeout 이 아님, 세션 종료), TLS 는 쓰기 timeout 후 다시 쓸 수 없으므로 항상 ErrBroken
+//
+// version 2: seed 압축 y 의 a 를 SHAKE128 로 복원 (com_utils.SeededCiphertext), v1 peer 와는 ErrVersion 으로 연결 거부
+// Conn 은 payload 버퍼와 Frame 을 재사용 → Recv 가 돌려준 Frame / Payload 는 다음 Recv 전까지만 유효
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	Magic   = "CPEC"
+	Version = 2
+
+	headerSize = 4 + 1 + 1 + 8 + 4
+	crcSize    = 4
+
+	// 깨진 length 로 큰 버퍼를 잡지 않도록 (N=2^12 암호문 ≈ 64 KB)
+	MaxPayload = 16 << 20
+
+	// HELLO / BYE / RESET / ERROR payload 상한 (JSON, 에러 문자열)
+	MaxControlPayload = 4 << 10
+
+	// 받는 payload 버퍼를 늘리는 단위 (이보다 작은 프레임은 한 번에)
+	recvChunk = 64 << 10
+)
+
+// 메시지 종류
+type Type uint8
+
+const (
+	TypeHello Type = iota + 1 // 연결 시작 (양쪽 spec 확인, payload = Hello JSON)
+	TypeY                     // plant → controller, seed 압축 y 암호문
+	TypeU                     // controller → plant, u 암호문
+	TypeBye                   // 정상 종료
+	TypeReset                 // 제어기 상태를 초기 xCtPack 으로 되돌림 (controller 는 RESET 으로 응답)
+	TypeError                 // payload = 에러 문자열
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeHello:
+		return "HELLO"
+	case TypeY:
+		return "Y"
+	case TypeU:
+		return "U"
+	case TypeBye:
+		return "BYE"
+	case TypeReset:
+		return "RESET"
+	case TypeError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("Type(%d)", uint8(t))
+}
+
+var (
+	ErrChecksum = errors.New("protocol: CRC32 mismatch")
+	ErrVersion  = errors.New("protocol: unsupported version")
+	ErrTooLarge = errors.New("protocol: payload too large")
+	ErrBroken   = errors.New("protocol: frame cut off mid-write, connection closed")
+)
+
+// 타입별 payload 상한 (0 = 기본값)
+type limits [TypeError + 1]int
+
+func (l *limits) get(t Type) int {
+	if int(t) < len(l) && l[t] > 0 {
+		return l[t]
+	}
+	if t == TypeY || t == TypeU {
+		return MaxPayload
+	}
+	return MaxControlPayload
+}
+
+func (l *limits) set(t Type, n int) {
+	if int(t) < len(l) {
+		l[t] = min(max(n, 0), MaxPayload)
+	}
+}
+
+// deadline 초과인지 (세션은 계속 쓸 수 있음)
+func IsTimeout(err error) bool {
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+type Frame struct {
+	Type    Type
+	Seq     uint64
+	Payload []byte
+}
+
+// payload 를 암호문 등으로 읽기 (rlwe.Ciphertext, com_utils.SeededCiphertext 등)
+// UnmarshalBinary 가 있으면 payload 를 바로 넘김 (com_utils.CtWire 등은 버퍼를 재사용해서 할당 없음)
+func (f *Frame) Decode(obj io.ReaderFrom) error {
+	var err error
+	if u, ok := obj.(encoding.BinaryUnmarshaler); ok {
+		err = u.UnmarshalBinary(f.Payload)
+	} else {
+		_, err = obj.ReadFrom(bufio.NewReader(bytes.NewReader(f.Payload)))
+	}
+	if err != nil {
+		return fmt.Errorf("protocol: decode %s payload: %w", f.Type, err)
+	}
+	return nil
+}
+
+// HELLO payload
+type Hello struct {
+	Role       string `json:"role"`               // 보내는 쪽 (plant / controller)
+	SpecSHA256 string `json:"specSha256"`         // manifest 의 spec fingerprint, 다르면 연결 거부
+	BundleID   string `json:"bundleId,omitempty"` // plant 가 쓸 controller bundle (keygen -out 폴더 이름), controller 가 여러 bundle 을 서비스할 때
+}
+
+func (f *Frame) Hello() (*Hello, error) {
+	h := new(Hello)
+	if err := json.Unmarshal(f.Payload, h); err != nil {
+		return nil, fmt.Errorf("protocol: bad HELLO payload: %w", err)
+	}
+	return h, nil
+}
+
+// ERROR payload 를 에러로
+func (f *Frame) Err() error {
+	return fmt.Errorf("protocol: peer error (seq %d): %s", f.Seq, f.Payload)
+}
+
+// plant / controller 가 쓰는 프레임 송수신 (Conn = TCP / TLS, UDPConn = UDP)
+type Transport interface {
+	Send(t Type, seq uint64, body io.WriterTo) (int, error)
+	SendBytes(t Type, seq uint64, payload []byte) (int, error)
+	SendHello(h Hello) (int, error)
+	SendError(seq uint64, err error) (int, error)
+	Recv() (*Frame, int, error)
+	RecvUntil(deadline time.Time) (*Frame, int, error)
+	SetMaxPayload(t Type, n int)
+}
+
+var (
+	_ Transport = (*Conn)(nil)
+	_ Transport = (*UDPConn)(nil)
+)
+
+// net.Conn 의 deadline 부분
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// 한 연결의 읽기/쓰기 (동시에 한 goroutine 씩만 Send, Recv 가능)
+type Conn struct {
+	rw  io.ReadWriter
+	dl  deadliner // rw 가 net.Conn 이 아니면 nil
+	tls bool      // 쓰기 timeout 이 연결을 깨뜨림
+	r   *bufio.Reader
+	w   *bufio.Writer
+	buf bytes.Buffer // payload 직렬화 버퍼 (재사용)
+
+	wrote  int64 // rw 로 실제로 나간 바이트 (프레임 중간에 끊겼는지 판단)
+	broken error // ErrBroken 뒤로는 Send 가 계속 실패
+
+	// 보내는 프레임의 header / CRC (bufio 를 거쳐 rw 로 넘어가므로 스택 대신 Conn 에, 스텝마다 할당 없음)
+	sendHdr  [headerSize]byte
+	sendTail [crcSize]byte
+
+	// 한 번의 Send / Recv 에 거는 deadline (0 = 없음, rw 가 net.Conn 이 아니면 무시)
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	Skipped int64 // 다시 맞추느라 버린 바이트 수
+
+	// deadline 으로 중간에 끊긴 프레임 (다음 Recv 에서 이어 읽음)
+	pending  bool
+	pendHdr  [headerSize]byte
+	pendSize int // payload + CRC
+	pendN    int
+
+	limits limits
+	body   []byte // payload + CRC 버퍼 (재사용, 더 큰 프레임이 들어오는 만큼 늘림)
+	frame  Frame  // Recv 가 돌려주는 Frame (재사용)
+}
+
+func NewConn(rw io.ReadWriter) *Conn {
+	dl, _ := rw.(deadliner)
+	_, isTLS := rw.(*tls.Conn)
+	c := &Conn{rw: rw, dl: dl, tls: isTLS, r: bufio.NewReader(rw)}
+	c.w = bufio.NewWriter(connWriter{c})
+	return c
+}
+
+// rw 로 나간 바이트를 셈 (bufio.Writer 아래)
+type connWriter struct{ c *Conn }
+
+func (w connWriter) Write(p []byte) (int, error) {
+	n, err := w.c.rw.Write(p)
+	w.c.wrote += int64(n)
+	return n, err
+}
+
+// 받을 t 프레임의 payload 상한 (manifest 파라미터로 정해지는 암호문 크기, com_utils.FrameLimits)
+func (c *Conn) SetMaxPayload(t Type, n int) {
+	c.limits.set(t, n)
+}
+
+// bufio.Writer 는 에러 후 계속 실패하므로 버리고 새로
+// sent > 0 (프레임 일부가 나감) 이거나 TLS 면 스트림을 되돌릴 수 없으므로 연결을 닫고 ErrBroken
+func (c *Conn) writeFailed(err error, sent int64) error {
+	c.w.Reset(connWriter{c})
+	if sent == 0 && !c.tls {
+		return err
+	}
+	c.broken = fmt.Errorf("%w (%d bytes sent, %v)", ErrBroken, sent, err)
+	if cl, ok := c.rw.(io.Closer); ok {
+		cl.Close()
+	}
+	return c.broken
+}
+
+func after(d time.Duration) time.Time {
+	if d <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(d)
+}
+
+// body 를 직렬화해서 한 프레임으로 보냄 (body == nil 이면 빈 payload), 보낸 바이트 수 반환
+func (c *Conn) Send(t Type, seq uint64, body io.WriterTo) (int, error) {
+	c.buf.Reset()
+	if body != nil {
+		if _, err := body.WriteTo(&c.buf); err != nil {
+			return 0, fmt.Errorf("protocol: encode %s payload: %w", t, err)
+		}
+	}
+	return c.SendBytes(t, seq, c.buf.Bytes())
+}
+
+func (c *Conn) SendBytes(t Type, seq uint64, payload []byte) (int, error) {
+	if c.broken != nil {
+		return 0, c.broken
+	}
+	if len(payload) > MaxPayload {
+		return 0, ErrTooLarge
+	}
+	hdr := &c.sendHdr
+	copy(hdr[:4], Magic)
+	hdr[4] = Version
+	hdr[5] = byte(t)
+	binary.BigEndian.PutUint64(hdr[6:14], seq)
+	binary.BigEndian.PutUint32(hdr[14:18], uint32(len(payload)))
+
+	tail := &c.sendTail
+	binary.BigEndian.PutUint32(tail[:], checksum(hdr[:], payload))
+
+	if c.dl != nil {
+		if err := c.dl.SetWriteDeadline(after(c.WriteTimeout)); err != nil {
+			return 0, err
+		}
+	}
+	start := c.wrote
+	for _, b := range [][]byte{hdr[:], payload, tail[:]} {
+		if _, err := c.w.Write(b); err != nil {
+			return 0, c.writeFailed(err, c.wrote-start)
+		}
+	}
+	if err := c.w.Flush(); err != nil {
+		return 0, c.writeFailed(err, c.wrote-start)
+	}
+	return headerSize + len(payload) + crcSize, nil
+}
+
+func (c *Conn) SendHello(h Hello) (int, error) {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return 0, err
+	}
+	return c.SendBytes(TypeHello, 0, b)
+}
+
+func (c *Conn) SendError(seq uint64, err error) (int, error) {
+	return c.SendBytes(TypeError, seq, errorPayload(err))
+}
+
+// ERROR payload (받는 쪽 MaxControlPayload 에 맞춰 자름)
+func errorPayload(err error) []byte {
+	msg := err.Error()
+	return []byte(msg[:min(len(msg), MaxControlPayload)])
+}
+
+// magic 부터 payload 끝까지의 CRC32 (IEEE)
+func checksum(hdr, payload []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE(hdr), crc32.IEEETable, payload)
+}
+
+// 다음 프레임 (ReadTimeout 적용)
+func (c *Conn) Recv() (*Frame, int, error) {
+	return c.RecvUntil(after(c.ReadTimeout))
+}
+
+// deadline 까지 다음 프레임 (zero = 무한 대기), 읽은 바이트 수 포함, 건너뛴 바이트는 Skipped 에 누적
+// ErrChecksum 이면 그 프레임은 이미 소비됐으므로 계속 Recv 할 수 있음
+// IsTimeout 이면 읽던 부분은 남아 있으므로 역시 계속 Recv 할 수 있음
+// 반환 Frame 과 Payload 는 Conn 의 버퍼 (다음 Recv 에서 덮어씀)
+func (c *Conn) RecvUntil(deadline time.Time) (*Frame, int, error) {
+	if c.dl != nil {
+		if err := c.dl.SetReadDeadline(deadline); err != nil {
+			return nil, 0, err
+		}
+	}
+	if !c.pending {
+		if err := c.sync(); err != nil {
+			return nil, 0, err
+		}
+		hdr, err := c.r.Peek(headerSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		copy(c.pendHdr[:], hdr)
+		c.r.Discard(headerSize)
+		if c.pendHdr[4] != Version {
+			return nil, headerSize, fmt.Errorf("%w: %d (want %d)", ErrVersion, c.pendHdr[4], Version)
+		}
+		t, size := Type(c.pendHdr[5]), int64(binary.BigEndian.Uint32(c.pendHdr[14:18]))
+		if limit := c.limits.get(t); size > int64(limit) {
+			return nil, headerSize, fmt.Errorf("%w: %s with %d bytes (limit %d)", ErrTooLarge, t, size, limit)
+		}
+		c.pendSize = int(size) + crcSize
+		c.pendN = 0
+		c.pending = true
+	}
+	for c.pendN < c.pendSize {
+		if c.pendN == len(c.body) {
+			c.grow()
+		}
+		k, err := c.r.Read(c.body[c.pendN:min(len(c.body), c.pendSize)])
+		c.pendN += k
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	hdr, body := &c.pendHdr, c.body[:c.pendSize]
+	c.pending = false
+
+	size := len(body) - crcSize
+	f := &c.frame
+	*f = Frame{Type: Type(hdr[5]), Seq: binary.BigEndian.Uint64(hdr[6:14]), Payload: body[:size]}
+	n := headerSize + len(body)
+	if checksum(hdr[:], f.Payload) != binary.BigEndian.Uint32(body[size:]) {
+		return f, n, fmt.Errorf("%w (%s seq %d)", ErrChecksum, f.Type, f.Seq)
+	}
+	return f, n, nil
+}
+
+// 받은 만큼 다 찬 body 를 늘림 (두 배씩, 읽는 중인 프레임 크기까지)
+func (c *Conn) grow() {
+	n := min(max(2*len(c.body), recvChunk), c.pendSize)
+	body := make([]byte, n)
+	copy(body, c.body[:c.pendN])
+	c.body = body
+}
+
+// magic 이 나올 때까지 한 바이트씩 버림
+func (c *Conn) sync() error {
+	for {
+		b, err := c.r.Peek(len(Magic))
+		if err != nil {
+			return err
+		}
+		if string(b) == Magic {
+			return nil
+		}
+		if _, err := c.r.Discard(1); err != nil {
+			return err
+		}
+		c.Skipped++
+	}
+}
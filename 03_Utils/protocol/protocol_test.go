@@ -0,0 +1,152 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// SendBytes 가 쓰는 프레임 바이트 그대로
+func frameBytes(t Type, seq uint64, payload []byte) []byte {
+	var b bytes.Buffer
+	c := NewConn(&b)
+	if _, err := c.SendBytes(t, seq, payload); err != nil {
+		panic(err)
+	}
+	return b.Bytes()
+}
+
+func TestConnRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("junk") // 앞의 쓰레기는 sync 가 건너뜀
+	b.Write(frameBytes(TypeY, 7, []byte("ciphertext")))
+	b.Write(frameBytes(TypeBye, 8, nil))
+
+	c := NewConn(&b)
+	f, n, err := c.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Type != TypeY || f.Seq != 7 || string(f.Payload) != "ciphertext" || n != headerSize+10+crcSize {
+		t.Fatalf("got %s seq %d %q (%d bytes)", f.Type, f.Seq, f.Payload, n)
+	}
+	if c.Skipped != 4 {
+		t.Fatalf("skipped %d bytes, want 4", c.Skipped)
+	}
+	if f, _, err = c.Recv(); err != nil || f.Type != TypeBye || len(f.Payload) != 0 {
+		t.Fatalf("got %v, %v", f, err)
+	}
+}
+
+func TestConnChecksum(t *testing.T) {
+	fr := frameBytes(TypeY, 1, []byte("payload"))
+	fr[headerSize] ^= 1
+	b := bytes.NewBuffer(fr)
+	b.Write(frameBytes(TypeY, 2, []byte("ok")))
+
+	c := NewConn(b)
+	if _, _, err := c.Recv(); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("want ErrChecksum, got %v", err)
+	}
+	if f, _, err := c.Recv(); err != nil || f.Seq != 2 {
+		t.Fatalf("next frame: %v, %v", f, err)
+	}
+}
+
+// 읽기 deadline 에 걸린 프레임은 다음 Recv 에서 이어 읽음
+func TestConnPartialFrame(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	payload := bytes.Repeat([]byte{0xAB}, 3000)
+	fr := frameBytes(TypeY, 3, payload)
+	cut := headerSize + 1000
+
+	c := NewConn(b)
+	go a.Write(fr[:cut])
+	if _, _, err := c.RecvUntil(time.Now().Add(50 * time.Millisecond)); !IsTimeout(err) {
+		t.Fatalf("want timeout, got %v", err)
+	}
+	go a.Write(fr[cut:])
+	f, _, err := c.RecvUntil(time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Seq != 3 || !bytes.Equal(f.Payload, payload) {
+		t.Fatalf("resumed frame differs (seq %d, %d bytes)", f.Seq, len(f.Payload))
+	}
+}
+
+// header 의 length 만 큰 프레임으로 버퍼를 미리 잡지 않음
+func TestConnGrowsWithData(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	fr := frameBytes(TypeY, 1, make([]byte, MaxPayload))
+	c := NewConn(b)
+	go a.Write(fr[:headerSize+100])
+	if _, _, err := c.RecvUntil(time.Now().Add(50 * time.Millisecond)); !IsTimeout(err) {
+		t.Fatalf("want timeout, got %v", err)
+	}
+	if len(c.body) > recvChunk {
+		t.Fatalf("buffer %d bytes after 100 payload bytes", len(c.body))
+	}
+}
+
+func TestConnMaxPayload(t *testing.T) {
+	var b bytes.Buffer
+	b.Write(frameBytes(TypeY, 1, make([]byte, 2000)))
+	b.Write(frameBytes(TypeHello, 0, make([]byte, MaxControlPayload+1)))
+
+	c := NewConn(&b)
+	c.SetMaxPayload(TypeY, 1000)
+	if _, _, err := c.Recv(); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Y over limit: want ErrTooLarge, got %v", err)
+	}
+	if _, _, err := c.Recv(); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("HELLO over MaxControlPayload: want ErrTooLarge, got %v", err)
+	}
+}
+
+// 한 바이트도 안 나간 쓰기 timeout 은 miss (연결은 그대로)
+func TestConnWriteTimeoutUnsent(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	c := NewConn(a)
+	c.WriteTimeout = 20 * time.Millisecond
+	if _, err := c.SendBytes(TypeU, 1, []byte("late")); !IsTimeout(err) {
+		t.Fatalf("want timeout, got %v", err)
+	}
+	go c.SendBytes(TypeU, 2, []byte("next"))
+	f, _, err := NewConn(b).RecvUntil(time.Now().Add(time.Second))
+	if err != nil || f.Seq != 2 || string(f.Payload) != "next" {
+		t.Fatalf("got %v, %v", f, err)
+	}
+}
+
+// 프레임 중간에 걸린 쓰기 timeout 은 연결을 닫음 (받는 쪽 스트림이 어긋나므로)
+func TestConnWriteTimeoutPartial(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	go b.Read(make([]byte, 10))
+	c := NewConn(a)
+	c.WriteTimeout = 50 * time.Millisecond
+	_, err := c.SendBytes(TypeU, 1, make([]byte, 100))
+	if !errors.Is(err, ErrBroken) || IsTimeout(err) {
+		t.Fatalf("want ErrBroken (not a timeout), got %v", err)
+	}
+	if _, err := c.SendBytes(TypeU, 2, nil); !errors.Is(err, ErrBroken) {
+		t.Fatalf("send after break: %v", err)
+	}
+	if _, err := b.Read(make([]byte, 1)); err == nil {
+		t.Fatal("connection still open")
+	}
+}
@@ -0,0 +1,101 @@
+package protocol
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// 상호 인증 TLS (02_Offline_task/certs.go 로 만든 lab CA 기준)
+//
+// controller 는 ServerAuth, plant 는 ClientAuth 인증서만 받음 → 같은 CA 의 plant 인증서로 controller 행세를 할 수 없음
+// controller 는 Accept 직후 Handshake 로 plant 인증서를 확인하고, 실패하면 HELLO 전에 연결을 끊음 (암호문은 읽지 않음)
+type TLSFiles struct {
+	CA   string // lab CA 인증서 (PEM)
+	Cert string // 자기 인증서 (PEM)
+	Key  string // 자기 개인키 (PEM)
+}
+
+// 셋 다 비어 있으면 TLS 를 쓰지 않음
+func (f TLSFiles) Enabled() bool {
+	return f.CA != "" || f.Cert != "" || f.Key != ""
+}
+
+func (f TLSFiles) load() (*x509.CertPool, tls.Certificate, error) {
+	if f.CA == "" || f.Cert == "" || f.Key == "" {
+		return nil, tls.Certificate{}, errors.New("tls: need CA, certificate and key (mutual authentication)")
+	}
+	pem, err := os.ReadFile(f.CA)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("tls: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, tls.Certificate{}, fmt.Errorf("tls: no certificate in %s", f.CA)
+	}
+	cert, err := tls.LoadX509KeyPair(f.Cert, f.Key)
+	if err != nil {
+		return nil, tls.Certificate{}, fmt.Errorf("tls: %w", err)
+	}
+	return pool, cert, nil
+}
+
+// controller 쪽: CA 가 서명한 plant (client) 인증서가 없으면 handshake 실패
+func ServerTLS(f TLSFiles) (*tls.Config, error) {
+	pool, cert, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
+// plant 쪽: serverName 은 controller 인증서의 SAN (IP 또는 호스트 이름)
+func ClientTLS(f TLSFiles, serverName string) (*tls.Config, error) {
+	pool, cert, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   serverName,
+	}, nil
+}
+
+// timeout 안에 handshake 를 끝내고 상대 인증서의 CN 을 반환 (인증 실패면 에러)
+func Handshake(conn *tls.Conn, timeout time.Duration) (string, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return "", fmt.Errorf("tls handshake: %w", err)
+	}
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", errors.New("tls handshake: peer sent no certificate")
+	}
+	return certs[0].Subject.CommonName, nil
+}
+
+// "host:port" 에서 ServerName 으로 쓸 host
+func ServerName(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
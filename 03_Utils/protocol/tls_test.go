@@ -0,0 +1,204 @@
+package protocol
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 02_Offline_task/certs.go 와 같은 모양의 테스트 CA
+type testCA struct {
+	dir  string
+	name string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, dir, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &testCA{dir: dir, name: name, key: key}
+	if ca.cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, name+".crt"), "CERTIFICATE", der)
+	return ca
+}
+
+// usage 하나짜리 인증서 → TLSFiles (CA 는 이 CA)
+func (ca *testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) TLSFiles {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(ca.dir, ca.name+"_"+cn)
+	writePEM(t, base+".crt", "CERTIFICATE", der)
+	writePEM(t, base+".key", "EC PRIVATE KEY", kder)
+	return TLSFiles{CA: filepath.Join(ca.dir, ca.name+".crt"), Cert: base + ".crt", Key: base + ".key"}
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type tlsResult struct {
+	cn       string // controller 가 본 plant CN
+	frame    *Frame // controller 가 읽은 첫 프레임
+	ctrlErr  error
+	plantErr error // plant 의 handshake 에러
+}
+
+// controller 처럼 Accept → Handshake → (성공하면) 첫 프레임, plant 는 handshake 후 HELLO
+func tlsSession(t *testing.T, server, client *tls.Config) tlsResult {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	res := make(chan tlsResult, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			res <- tlsResult{ctrlErr: err}
+			return
+		}
+		defer conn.Close()
+		cn, err := Handshake(conn.(*tls.Conn), time.Second)
+		if err != nil {
+			res <- tlsResult{ctrlErr: err} // 프레임은 읽지 않음
+			return
+		}
+		pc := NewConn(conn)
+		pc.ReadTimeout = time.Second
+		f, _, err := pc.Recv()
+		res <- tlsResult{cn: cn, frame: f, ctrlErr: err}
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+	if err == nil {
+		defer conn.Close()
+		if _, err = Handshake(conn, time.Second); err == nil {
+			// TLS 1.3 은 client 쪽 handshake 가 server 의 client 인증서 확인 전에 끝날 수 있음 → 보내 봄
+			NewConn(conn).SendHello(Hello{Role: "plant"})
+		}
+	}
+	r := <-res
+	r.plantErr = err
+	return r
+}
+
+func TestTLSMutualAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir, "ca")
+	other := newTestCA(t, dir, "other")
+	ctrl := ca.issue(t, "controller", x509.ExtKeyUsageServerAuth)
+	plant := ca.issue(t, "plant1", x509.ExtKeyUsageClientAuth)
+
+	serverConf, err := ServerTLS(ctrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConf := func(f TLSFiles) *tls.Config {
+		conf, err := ClientTLS(f, ServerName("127.0.0.1:0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conf
+	}
+
+	r := tlsSession(t, serverConf, clientConf(plant))
+	if r.ctrlErr != nil || r.plantErr != nil || r.cn != "plant1" || r.frame == nil || r.frame.Type != TypeHello {
+		t.Fatalf("valid plant: %+v", r)
+	}
+
+	noCert := clientConf(plant)
+	noCert.Certificates = nil
+	// 다른 CA 의 plant 인증서 (CA 는 맞는 것으로 controller 는 믿음)
+	foreign := other.issue(t, "plant1", x509.ExtKeyUsageClientAuth)
+	foreign.CA = plant.CA
+	for name, conf := range map[string]*tls.Config{
+		"no certificate":     noCert,
+		"other CA":           clientConf(foreign),
+		"ServerAuth only":    clientConf(ctrl), // controller 인증서로 plant 행세
+		"ServerAuth (other)": clientConf(ca.issue(t, "plant2", x509.ExtKeyUsageServerAuth)),
+	} {
+		r := tlsSession(t, serverConf, conf)
+		if r.ctrlErr == nil {
+			t.Errorf("%s: controller handshake succeeded", name)
+		}
+		if r.frame != nil {
+			t.Errorf("%s: controller read a %s frame", name, r.frame.Type)
+		}
+	}
+
+	// plant 인증서 (ClientAuth) 로 controller 행세 → plant 가 거부
+	fake, err := ServerTLS(plant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := tlsSession(t, fake, clientConf(plant)); r.plantErr == nil {
+		t.Error("plant accepted a plant certificate as the controller")
+	} else if r.frame != nil {
+		t.Errorf("fake controller read a %s frame", r.frame.Type)
+	}
+}
+
+func TestTLSFilesRequired(t *testing.T) {
+	if (TLSFiles{}).Enabled() {
+		t.Fatal("empty TLSFiles enabled")
+	}
+	if _, err := ServerTLS(TLSFiles{CA: "ca.crt"}); err == nil {
+		t.Fatal("ServerTLS without certificate and key succeeded")
+	}
+}
@@ -0,0 +1,522 @@
+package protocol
+
+// UDP 전송 (TCP 의 head-of-line blocking 없이 늦은 프레임은 버림)
+//
+//	magic "CPEU"(4) | version(1) | type(1) | seq(8) | index(2) | count(2) | length(4) | chunk | CRC32(4)
+//
+// 프레임 (type, seq, payload) 하나를 MaxChunk 바이트씩 datagram count 개로 나눠 보냄 (N=2^12 u ≈ 64 KB → 55 개)
+// length 는 payload 전체 길이, CRC32 는 datagram 마다 (깨진 datagram 은 버림)
+// 받는 쪽은 (type, seq) 별로 모으고 첫 조각부터 FrameTimeout 안에 다 못 모은 프레임은 버림 (Incomplete)
+// 재전송은 없음: 잃어버린 y / u 는 deadline miss 와 같음 (plant 는 hold / shadow, controller 는 read miss)
+//
+// 인증이 없으므로 받는 쪽 메모리는 상한을 둠
+// - length 는 타입별 상한 (SetMaxPayload), UDP 는 조각 버퍼를 첫 조각에 잡으므로 설정 전에는 Y / U 도 MaxControlPayload
+// - 세션마다 모으는 중인 프레임은 udpMaxPartials 개까지 (넘으면 새 프레임 조각을 버림)
+// - listener 는 처음 보는 주소의 datagram 이 한 조각짜리 HELLO 일 때만 세션을 만들고, 세션은 udpMaxSessions 개까지
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	udpMagic       = "CPEU"
+	fragHeaderSize = 4 + 1 + 1 + 8 + 2 + 2 + 4
+
+	// datagram 하나의 payload 조각 (IPv6 최소 MTU 1280 안, IP 단편화 없음)
+	MaxChunk    = 1200
+	maxDatagram = fragHeaderSize + MaxChunk + crcSize
+
+	// 조각을 다 모으는 제한 시간 기본값 (샘플링 시간)
+	DefaultFrameTimeout = 30 * time.Millisecond
+
+	udpQueue   = 4096    // 세션별 수신 대기 datagram 수 (N=2^12 u 약 70 개 분량)
+	udpReadBuf = 4 << 20 // 소켓 수신 버퍼 (y/u 조각이 한꺼번에 옴)
+	udpBacklog = 16      // Accept 대기 세션 수
+
+	udpMaxPartials = 8  // 세션별로 모으는 중인 프레임 수 (정상이면 y / u 1~2 개)
+	udpMaxSessions = 32 // listener 의 동시 세션 수
+	udpGapWindow   = 64 // 마지막 seq 에서 이만큼 안의 건너뛴 seq 는 기억 (늦게 오면 Missing 에서 뺌)
+
+	udpReadBackoff = 10 * time.Millisecond // listener 소켓 읽기 에러 뒤 기다림 (최대 100 배까지 늘림)
+)
+
+// IdleTimeout 동안 상대에게서 datagram 이 하나도 안 옴 (UDP 에는 연결 끊김이 없으므로 세션 종료 기준)
+var ErrIdle = errors.New("protocol: peer idle")
+
+// 한 방향 (받은 쪽 기준) 손실 통계
+type UDPStats struct {
+	FramesSent, DatagramsSent int64
+	FramesRecv, DatagramsRecv int64 // 다 모은 프레임 / 받은 datagram
+
+	Incomplete int64 // FrameTimeout 안에 조각을 다 못 모아 버린 프레임
+	Missing    int64 // seq 가 건너뛴 Y / U 프레임 (조각이 하나도 안 옴, plant 의 U 는 controller 가 y 를 잃은 경우도 포함)
+	Late       int64 // 더 큰 seq 뒤에 다 모인 프레임 (그대로 전달, stale 처리는 위에서, Missing 에서는 빠짐)
+	Dup        int64 // 이미 받은 (또는 버린) Y / U 프레임의 조각 (버림)
+	Bad        int64 // CRC / 헤더가 틀리거나 length 상한, 모으는 프레임 수 상한을 넘은 datagram
+	Overruns   int64 // 수신 큐가 가득 차서 버린 datagram
+}
+
+// 받아야 했던 프레임 중 잃은 비율
+func (s UDPStats) FrameLoss() float64 {
+	lost := s.Incomplete + s.Missing
+	if lost+s.FramesRecv == 0 {
+		return 0
+	}
+	return float64(lost) / float64(lost+s.FramesRecv)
+}
+
+func (s UDPStats) String() string {
+	return fmt.Sprintf("frame loss %.2f%% (recv %d, incomplete %d, missing %d, late %d) | datagrams recv %d, bad %d, dup %d, overrun %d | sent %d frames / %d datagrams",
+		100*s.FrameLoss(), s.FramesRecv, s.Incomplete, s.Missing, s.Late,
+		s.DatagramsRecv, s.Bad, s.Dup, s.Overruns, s.FramesSent, s.DatagramsSent)
+}
+
+type fragKey struct {
+	t   Type
+	seq uint64
+}
+
+// 모으는 중인 프레임
+type partial struct {
+	buf   []byte
+	got   []bool
+	n     int // 받은 조각 수
+	bytes int // 받은 datagram 바이트 합
+	first time.Time
+}
+
+// 상대 하나와의 UDP 세션 (Conn 과 같은 메서드, 동시에 한 goroutine 씩만 Send, Recv 가능)
+type UDPConn struct {
+	sock   net.PacketConn
+	peer   net.Addr
+	in     chan []byte // 소켓을 읽는 goroutine 이 넣음 (소켓이 닫히면 close)
+	detach func()      // Close 때 (listener 세션이면 listener 에서 빼고, Dial 이면 소켓을 닫음)
+	done   chan struct{}
+	once   sync.Once
+
+	// ReadTimeout: Recv 한 번의 대기 (0 = 없음)
+	// FrameTimeout: 첫 조각부터 프레임을 다 모으는 제한 (0 = DefaultFrameTimeout)
+	// IdleTimeout: 이 시간 동안 아무것도 안 오면 ErrIdle (0 = 없음)
+	ReadTimeout, FrameTimeout, IdleTimeout time.Duration
+
+	buf      bytes.Buffer // payload 직렬화 버퍼 (재사용)
+	out      []byte       // datagram 버퍼 (재사용)
+	parts    map[fragKey]*partial
+	limits   limits
+	last     map[Type]uint64  // Y / U 별 마지막 seq (Missing, Late 계산)
+	gaps     map[fragKey]bool // Missing 으로 센 seq (udpGapWindow 안만)
+	lastRecv time.Time
+
+	stats    UDPStats
+	overruns atomic.Int64
+}
+
+func newUDPConn(sock net.PacketConn, peer net.Addr) *UDPConn {
+	c := &UDPConn{
+		sock:     sock,
+		peer:     peer,
+		in:       make(chan []byte, udpQueue),
+		done:     make(chan struct{}),
+		out:      make([]byte, maxDatagram),
+		parts:    make(map[fragKey]*partial),
+		last:     make(map[Type]uint64),
+		gaps:     make(map[fragKey]bool),
+		lastRecv: time.Now(),
+	}
+	c.limits.set(TypeY, MaxControlPayload)
+	c.limits.set(TypeU, MaxControlPayload)
+	return c
+}
+
+// 큐가 가득 차면 버림 (읽는 쪽이 느려도 소켓을 읽는 goroutine 은 막히지 않음)
+func (c *UDPConn) push(d []byte) {
+	select {
+	case c.in <- d:
+	default:
+		c.overruns.Add(1)
+	}
+}
+
+func setReadBuffer(sock net.PacketConn) {
+	if uc, ok := sock.(*net.UDPConn); ok {
+		uc.SetReadBuffer(udpReadBuf) // 실패해도 OS 기본값으로 동작
+	}
+}
+
+// plant 쪽: addr 의 controller 와 UDP 세션
+func DialUDP(addr string) (*UDPConn, error) {
+	peer, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	sock, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	setReadBuffer(sock)
+	c := newUDPConn(sock, peer)
+	c.detach = func() { sock.Close() }
+	go func() {
+		defer close(c.in)
+		for {
+			d := make([]byte, maxDatagram)
+			n, from, err := sock.ReadFrom(d)
+			if err != nil {
+				return
+			}
+			if from.String() == peer.String() {
+				c.push(d[:n])
+			}
+		}
+	}()
+	return c, nil
+}
+
+func (c *UDPConn) RemoteAddr() net.Addr { return c.peer }
+
+// 받을 t 프레임의 payload 상한 (Conn.SetMaxPayload 와 같음, 넘는 length 의 조각은 Bad, Y / U 는 받기 전에 설정)
+func (c *UDPConn) SetMaxPayload(t Type, n int) {
+	c.limits.set(t, n)
+}
+
+// 다른 goroutine 에서 불러도 됨 (기다리던 Recv 는 net.ErrClosed)
+func (c *UDPConn) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+		c.detach()
+	})
+	return nil
+}
+
+// 누적 통계 (오래된 미완성 프레임을 정리한 뒤)
+func (c *UDPConn) Stats() UDPStats {
+	c.expire(time.Now())
+	s := c.stats
+	s.Overruns = c.overruns.Load()
+	return s
+}
+
+func (c *UDPConn) Send(t Type, seq uint64, body io.WriterTo) (int, error) {
+	c.buf.Reset()
+	if body != nil {
+		if _, err := body.WriteTo(&c.buf); err != nil {
+			return 0, fmt.Errorf("protocol: encode %s payload: %w", t, err)
+		}
+	}
+	return c.SendBytes(t, seq, c.buf.Bytes())
+}
+
+// payload 를 조각내서 보냄, 보낸 datagram 바이트 합 반환 (UDP 송신은 네트워크를 기다리지 않으므로 deadline 없음)
+func (c *UDPConn) SendBytes(t Type, seq uint64, payload []byte) (int, error) {
+	if len(payload) > MaxPayload {
+		return 0, ErrTooLarge
+	}
+	count := max(1, (len(payload)+MaxChunk-1)/MaxChunk)
+	sent := 0
+	for i := 0; i < count; i++ {
+		chunk := payload[i*MaxChunk : min((i+1)*MaxChunk, len(payload))]
+		d := c.out[:fragHeaderSize+len(chunk)+crcSize]
+		copy(d[:4], udpMagic)
+		d[4] = Version
+		d[5] = byte(t)
+		binary.BigEndian.PutUint64(d[6:14], seq)
+		binary.BigEndian.PutUint16(d[14:16], uint16(i))
+		binary.BigEndian.PutUint16(d[16:18], uint16(count))
+		binary.BigEndian.PutUint32(d[18:22], uint32(len(payload)))
+		copy(d[fragHeaderSize:], chunk)
+		binary.BigEndian.PutUint32(d[len(d)-crcSize:], crc32.ChecksumIEEE(d[:len(d)-crcSize]))
+		n, err := c.sock.WriteTo(d, c.peer)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+		c.stats.DatagramsSent++
+	}
+	c.stats.FramesSent++
+	return sent, nil
+}
+
+func (c *UDPConn) SendHello(h Hello) (int, error) {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return 0, err
+	}
+	return c.SendBytes(TypeHello, 0, b)
+}
+
+func (c *UDPConn) SendError(seq uint64, err error) (int, error) {
+	return c.SendBytes(TypeError, seq, errorPayload(err))
+}
+
+// 다음 프레임 (ReadTimeout 적용)
+func (c *UDPConn) Recv() (*Frame, int, error) {
+	return c.RecvUntil(after(c.ReadTimeout))
+}
+
+// deadline 까지 조각을 모아 다음 프레임 (zero = 무한 대기), 프레임의 datagram 바이트 합 포함
+// deadline 이 지나면 IsTimeout 에러 (모으던 조각은 FrameTimeout 까지 남아 있음), IdleTimeout 이면 ErrIdle
+func (c *UDPConn) RecvUntil(deadline time.Time) (*Frame, int, error) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		wake, idle := deadline, false
+		if c.IdleTimeout > 0 {
+			if t := c.lastRecv.Add(c.IdleTimeout); wake.IsZero() || t.Before(wake) {
+				wake, idle = t, true
+			}
+		}
+		var tc <-chan time.Time
+		if !wake.IsZero() {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(time.Until(wake))
+			tc = timer.C
+		}
+		select {
+		case d, ok := <-c.in:
+			if !ok {
+				return nil, 0, net.ErrClosed
+			}
+			c.lastRecv = time.Now()
+			if f, n := c.add(d, c.lastRecv); f != nil {
+				return f, n, nil
+			}
+		case <-c.done:
+			return nil, 0, net.ErrClosed
+		case <-tc:
+			if idle {
+				return nil, 0, fmt.Errorf("%w for %v", ErrIdle, c.IdleTimeout)
+			}
+			return nil, 0, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+// datagram 헤더 (CRC, 조각 번호 / 개수 / length 가 서로 맞는지까지)
+type frag struct {
+	key               fragKey
+	idx, count, total int
+	chunk             []byte
+}
+
+func parseFrag(d []byte) (frag, bool) {
+	if len(d) < fragHeaderSize+crcSize || string(d[:4]) != udpMagic || d[4] != Version ||
+		crc32.ChecksumIEEE(d[:len(d)-crcSize]) != binary.BigEndian.Uint32(d[len(d)-crcSize:]) {
+		return frag{}, false
+	}
+	f := frag{
+		key:   fragKey{t: Type(d[5]), seq: binary.BigEndian.Uint64(d[6:14])},
+		idx:   int(binary.BigEndian.Uint16(d[14:16])),
+		count: int(binary.BigEndian.Uint16(d[16:18])),
+		total: int(binary.BigEndian.Uint32(d[18:22])),
+		chunk: d[fragHeaderSize : len(d)-crcSize],
+	}
+	ok := f.count == max(1, (f.total+MaxChunk-1)/MaxChunk) && f.idx < f.count &&
+		len(f.chunk) == min(MaxChunk, f.total-f.idx*MaxChunk)
+	return f, ok
+}
+
+// 새 세션을 열 수 있는 datagram: 한 조각짜리 HELLO (payload 가 JSON)
+func isHello(d []byte) bool {
+	f, ok := parseFrag(d)
+	return ok && f.key.t == TypeHello && f.count == 1 && f.total <= MaxControlPayload && json.Valid(f.chunk)
+}
+
+// datagram 하나를 모으고 프레임이 완성되면 반환
+func (c *UDPConn) add(d []byte, now time.Time) (*Frame, int) {
+	c.stats.DatagramsRecv++
+	c.expire(now)
+	f, ok := parseFrag(d)
+	if !ok || f.total > c.limits.get(f.key.t) {
+		c.stats.Bad++
+		return nil, 0
+	}
+	key, idx, count, total, chunk := f.key, f.idx, f.count, f.total, f.chunk
+
+	p := c.parts[key]
+	if p == nil {
+		if c.handled(key) {
+			c.stats.Dup++
+			return nil, 0
+		}
+		if len(c.parts) >= udpMaxPartials {
+			c.stats.Bad++
+			return nil, 0
+		}
+		p = &partial{buf: make([]byte, total), got: make([]bool, count), first: now}
+		c.parts[key] = p
+	} else if len(p.buf) != total || len(p.got) != count {
+		c.stats.Bad++
+		return nil, 0
+	}
+	if p.got[idx] {
+		return nil, 0 // 중복
+	}
+	copy(p.buf[idx*MaxChunk:], chunk)
+	p.got[idx] = true
+	p.n++
+	p.bytes += len(d)
+	if p.n < count {
+		return nil, 0
+	}
+	delete(c.parts, key)
+	c.stats.FramesRecv++
+	if c.seen(key) {
+		c.stats.Late++
+	}
+	return &Frame{Type: key.t, Seq: key.seq, Payload: p.buf}, p.bytes
+}
+
+// FrameTimeout 이 지난 미완성 프레임 버림
+func (c *UDPConn) expire(now time.Time) {
+	limit := c.FrameTimeout
+	if limit <= 0 {
+		limit = DefaultFrameTimeout
+	}
+	for key, p := range c.parts {
+		if now.Sub(p.first) > limit {
+			delete(c.parts, key)
+			c.stats.Incomplete++
+			c.seen(key)
+		}
+	}
+}
+
+// Y / U 의 seq 기록, 건너뛴 seq 는 Missing 으로 셈 (이미 지난 seq 면 true)
+// 건너뛴 seq 는 gaps 에 기억해 두고 나중에 다 모이거나 (Late) 조각만 왔다가 버려지면 (Incomplete) Missing 에서 뺌
+// → 프레임 하나는 FramesRecv, Incomplete, Missing 중 한 곳에만
+func (c *UDPConn) seen(key fragKey) bool {
+	if key.t != TypeY && key.t != TypeU {
+		return false
+	}
+	last := c.last[key.t]
+	if key.seq <= last {
+		if c.gaps[key] {
+			delete(c.gaps, key)
+			c.stats.Missing--
+		}
+		return true
+	}
+	if last > 0 {
+		c.stats.Missing += int64(key.seq - last - 1)
+		for seq := max(last+1, key.seq-min(key.seq, udpGapWindow)); seq < key.seq; seq++ {
+			c.gaps[fragKey{key.t, seq}] = true
+		}
+	}
+	c.last[key.t] = key.seq
+	for k := range c.gaps {
+		if k.t == key.t && k.seq+udpGapWindow < key.seq {
+			delete(c.gaps, k) // 너무 오래됨, Missing 으로 남김
+		}
+	}
+	return false
+}
+
+// 이미 다 모았거나 버린 Y / U 프레임인지 (마지막 seq 이하이고 udpGapWindow 안인데 gaps 에 없음)
+// 같은 프레임이 두 번 오면 두 번째는 버림 (FramesRecv / Incomplete 를 두 번 세지 않게)
+func (c *UDPConn) handled(key fragKey) bool {
+	if key.t != TypeY && key.t != TypeU {
+		return false
+	}
+	last := c.last[key.t]
+	return key.seq <= last && key.seq+udpGapWindow >= last && !c.gaps[key]
+}
+
+// controller 쪽: 한 UDP 소켓에서 보낸 주소별로 세션을 나눔
+type UDPListener struct {
+	sock   net.PacketConn
+	accept chan *UDPConn
+
+	mu       sync.Mutex
+	sessions map[string]*UDPConn
+}
+
+func ListenUDP(addr string) (*UDPListener, error) {
+	sock, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	setReadBuffer(sock)
+	l := &UDPListener{sock: sock, accept: make(chan *UDPConn, udpBacklog), sessions: make(map[string]*UDPConn)}
+	go l.read()
+	return l, nil
+}
+
+// 소켓을 읽어서 세션별 큐로 (처음 보는 주소가 HELLO 를 보내면 새 세션을 Accept 로 넘김, 아니면 버림)
+func (l *UDPListener) read() {
+	defer func() {
+		l.mu.Lock()
+		for _, c := range l.sessions {
+			close(c.in)
+		}
+		l.sessions = nil
+		l.mu.Unlock()
+		close(l.accept)
+	}()
+	backoff := time.Duration(0)
+	for {
+		d := make([]byte, maxDatagram)
+		n, from, err := l.sock.ReadFrom(d)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			// ICMP unreachable 등은 한 번뿐이지만 계속 나는 에러에 바쁘게 돌지 않도록
+			backoff = min(max(2*backoff, udpReadBackoff), 100*udpReadBackoff)
+			time.Sleep(backoff)
+			continue
+		}
+		backoff = 0
+		key := from.String()
+		l.mu.Lock()
+		c := l.sessions[key]
+		if c == nil && isHello(d[:n]) && len(l.sessions) < udpMaxSessions {
+			c = newUDPConn(l.sock, from)
+			c.detach = func() {
+				l.mu.Lock()
+				if l.sessions[key] == c {
+					delete(l.sessions, key)
+				}
+				l.mu.Unlock()
+			}
+			select {
+			case l.accept <- c:
+				l.sessions[key] = c
+			default:
+				c = nil // Accept 가 밀려 있으면 이 datagram 은 버림
+			}
+		}
+		l.mu.Unlock()
+		if c != nil {
+			c.push(d[:n])
+		}
+	}
+}
+
+// 새 주소에서 온 HELLO 로 시작하는 세션 (listener 가 닫히면 net.ErrClosed)
+func (l *UDPListener) Accept() (*UDPConn, error) {
+	c, ok := <-l.accept
+	if !ok {
+		return nil, net.ErrClosed
+	}
+	return c, nil
+}
+
+func (l *UDPListener) Addr() net.Addr { return l.sock.LocalAddr() }
+
+func (l *UDPListener) Close() error { return l.sock.Close() }
@@ -0,0 +1,211 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// SendBytes 가 보낸 datagram 을 모으는 소켓
+type captureSock struct {
+	net.PacketConn
+	sent [][]byte
+}
+
+func (s *captureSock) WriteTo(p []byte, _ net.Addr) (int, error) {
+	s.sent = append(s.sent, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func datagrams(t Type, seq uint64, payload []byte) [][]byte {
+	s := &captureSock{}
+	if _, err := newUDPConn(s, nil).SendBytes(t, seq, payload); err != nil {
+		panic(err)
+	}
+	return s.sent
+}
+
+func TestUDPReassembly(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 500) // 5 조각
+	ds := datagrams(TypeU, 4, payload)
+	if len(ds) != 5 {
+		t.Fatalf("%d datagrams, want 5", len(ds))
+	}
+	c := newUDPConn(nil, nil)
+	c.SetMaxPayload(TypeU, len(payload))
+	now := time.Now()
+	var f *Frame
+	for i := len(ds) - 1; i >= 0; i-- { // 순서가 뒤바뀌어도
+		f, _ = c.add(ds[i], now)
+		if i > 0 && f != nil {
+			t.Fatalf("frame complete after %d datagrams", len(ds)-i)
+		}
+	}
+	if f == nil || f.Type != TypeU || f.Seq != 4 || !bytes.Equal(f.Payload, payload) {
+		t.Fatalf("reassembled frame differs: %v", f)
+	}
+	if len(c.parts) != 0 {
+		t.Fatalf("%d partial frames left", len(c.parts))
+	}
+}
+
+// length 가 상한을 넘는 조각은 버퍼를 잡지 않고 버림
+func TestUDPPayloadLimit(t *testing.T) {
+	ds := datagrams(TypeY, 1, make([]byte, 4*MaxChunk))
+	c := newUDPConn(nil, nil)
+	c.add(ds[0], time.Now()) // SetMaxPayload 전에는 MaxControlPayload
+	c.SetMaxPayload(TypeY, 3*MaxChunk)
+	c.add(ds[1], time.Now())
+	if len(c.parts) != 0 || c.stats.Bad != 2 {
+		t.Fatalf("parts %d, bad %d", len(c.parts), c.stats.Bad)
+	}
+	c.SetMaxPayload(TypeY, 4*MaxChunk)
+	c.add(ds[2], time.Now())
+	if len(c.parts) != 1 {
+		t.Fatalf("parts %d, want 1", len(c.parts))
+	}
+}
+
+// 모으는 중인 프레임은 udpMaxPartials 개까지
+func TestUDPPartialLimit(t *testing.T) {
+	c := newUDPConn(nil, nil)
+	c.SetMaxPayload(TypeY, 2*MaxChunk)
+	now := time.Now()
+	for seq := uint64(1); seq <= 2*udpMaxPartials; seq++ {
+		c.add(datagrams(TypeY, seq, make([]byte, 2*MaxChunk))[0], now)
+	}
+	if len(c.parts) != udpMaxPartials || c.stats.Bad != udpMaxPartials {
+		t.Fatalf("parts %d, bad %d (limit %d)", len(c.parts), c.stats.Bad, udpMaxPartials)
+	}
+}
+
+// listener 는 HELLO 로 시작하는 주소에만 세션을 만듦
+func TestUDPListenerHelloOnly(t *testing.T) {
+	l, err := ListenUDP("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	sock, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+	for _, d := range datagrams(TypeY, 1, []byte("not a hello")) {
+		sock.WriteTo(d, l.Addr())
+	}
+	time.Sleep(50 * time.Millisecond)
+	l.mu.Lock()
+	n := len(l.sessions)
+	l.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("%d sessions from a non-HELLO datagram", n)
+	}
+
+	pc, err := DialUDP(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	if _, err := pc.SendHello(Hello{Role: "plant"}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _, err := c.RecvUntil(time.Now().Add(time.Second))
+	if err != nil || f.Type != TypeHello {
+		t.Fatalf("got %v, %v", f, err)
+	}
+
+	// Close 는 다른 goroutine 에서 기다리는 Recv 를 깨움
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		c.Close()
+	}()
+	if _, _, err := c.Recv(); err != net.ErrClosed {
+		t.Fatalf("Recv after Close: %v", err)
+	}
+}
+
+// 순서가 바뀌거나 늦게 온 프레임은 FramesRecv / Incomplete / Missing 중 한 곳에만
+func TestUDPReorderAccounting(t *testing.T) {
+	c := newUDPConn(nil, nil)
+	c.SetMaxPayload(TypeY, 2*MaxChunk)
+	now := time.Now()
+	one := func(seq uint64) []byte { return datagrams(TypeY, seq, []byte("y"))[0] }
+	two := datagrams(TypeY, 5, make([]byte, 2*MaxChunk))
+
+	check := func(step string, recv, missing, late, incomplete, dup int64) {
+		t.Helper()
+		s := c.stats
+		if s.FramesRecv != recv || s.Missing != missing || s.Late != late || s.Incomplete != incomplete || s.Dup != dup {
+			t.Fatalf("%s: recv %d missing %d late %d incomplete %d dup %d, want %d %d %d %d %d",
+				step, s.FramesRecv, s.Missing, s.Late, s.Incomplete, s.Dup, recv, missing, late, incomplete, dup)
+		}
+	}
+
+	c.add(one(1), now)
+	c.add(two[0], now) // 5 는 조각 하나만
+	c.add(one(6), now) // 2, 3, 4, 5 건너뜀
+	check("gap", 2, 4, 0, 0, 0)
+
+	if f, _ := c.add(one(3), now); f == nil || f.Seq != 3 {
+		t.Fatalf("late frame not delivered: %v", f)
+	}
+	check("late", 3, 3, 1, 0, 0)
+
+	c.add(one(3), now) // 같은 프레임이 또
+	c.add(one(6), now)
+	check("dup", 3, 3, 1, 0, 2)
+
+	c.add(one(7), now.Add(2*DefaultFrameTimeout)) // 5 의 조각은 버려짐
+	check("expire", 4, 2, 1, 1, 2)
+	c.add(two[1], now.Add(2*DefaultFrameTimeout)) // 버린 프레임의 나머지 조각
+	check("after expire", 4, 2, 1, 1, 3)
+
+	if loss, want := c.stats.FrameLoss(), 3.0/7; loss != want { // 1..7 중 2, 4, 5 를 잃음
+		t.Errorf("frame loss %v, want %v", loss, want)
+	}
+
+	// udpGapWindow 보다 오래된 seq 는 기억하지 않음
+	c.add(one(7+2*udpGapWindow), now.Add(2*DefaultFrameTimeout))
+	if len(c.gaps) > udpGapWindow {
+		t.Fatalf("%d gaps kept, window %d", len(c.gaps), udpGapWindow)
+	}
+}
+
+// ReadFrom 이 계속 에러를 내는 소켓
+type errSock struct {
+	net.PacketConn
+	reads  atomic.Int64
+	closed atomic.Bool
+}
+
+func (s *errSock) ReadFrom([]byte) (int, net.Addr, error) {
+	s.reads.Add(1)
+	if s.closed.Load() {
+		return 0, nil, net.ErrClosed
+	}
+	return 0, nil, errors.New("read: connection refused")
+}
+
+// 읽기 에러가 계속 나도 listener 가 바쁘게 돌지 않음
+func TestUDPListenerReadBackoff(t *testing.T) {
+	sock := &errSock{}
+	l := &UDPListener{sock: sock, accept: make(chan *UDPConn, udpBacklog), sessions: make(map[string]*UDPConn)}
+	go l.read()
+	time.Sleep(100 * time.Millisecond)
+	sock.closed.Store(true)
+	if _, err := l.Accept(); err == nil {
+		t.Fatal("Accept after socket closed succeeded")
+	}
+	if n := sock.reads.Load(); n > 10 {
+		t.Fatalf("%d reads in 100ms", n)
+	}
+}
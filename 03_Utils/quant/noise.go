@@ -0,0 +1,89 @@
+package quant
+
+import (
+	"math"
+
+	com_utils "Encrypted_Cartpole/03_Utils"
+
+	RLWE "github.com/CDSL-EncryptedControl/CDSL/utils/core/RLWE"
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// 암호문 noise 표준편차 (plaintext 정수 단위, packing slot 계수 기준)
+// - Fresh: 새로 암호화한 RLWE
+// - Slot : EncPack 후 UnpackCt 한 slot 암호문 (fresh + automorphism key switching)
+// - Ext  : RGSW(0) 와의 외부곱 1회가 더하는 noise
+type Noise struct {
+	Fresh float64
+	Slot  float64
+	Ext   float64
+}
+
+// 실제 파라미터로 0 을 암호화해서 noise 측정 (trials 회의 RMS)
+func MeasureNoise(params rlwe.Parameters, tau int, trials int) Noise {
+	return MeasureNoiseSeeded(params, tau, trials, nil)
+}
+
+// MeasureNoise 를 -unsafe-seed 결정적 모드로 (keygen -auto-scales 의 스케일 선택까지 재현)
+func MeasureNoiseSeeded(params rlwe.Parameters, tau int, trials int, u *com_utils.UnsafeSeed) Noise {
+	ringQ := params.RingQ()
+	monomials, galEls := com_utils.PackSetup(params, tau)
+
+	kgen := u.KeyGenerator(params)
+	sk := kgen.GenSecretKeyNew()
+	rlk := kgen.GenRelinearizationKeyNew(sk)
+	gks := kgen.GenGaloisKeysNew(galEls, sk)
+
+	encRLWE := u.Encryptor(params, sk)
+	encRGSW := u.RGSWEncryptor(params, sk)
+	dec := rlwe.NewDecryptor(params, sk)
+	evalRLWE := rlwe.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk, gks...))
+	evalRGSW := rgsw.NewEvaluator(params, rlwe.NewMemEvaluationKeySet(rlk))
+
+	zeroRGSW := rgsw.NewCiphertext(params, params.MaxLevelQ(), params.MaxLevelP(), 0)
+	encRGSW.Encrypt(rlwe.NewPlaintext(params, params.MaxLevel()), zeroRGSW)
+
+	var fresh, slot, ext []float64
+	zeros := make([]int64, tau)
+	for t := 0; t < trials; t++ {
+		ct := RLWE.EncPack(zeros, tau, 1, *encRLWE, ringQ, params)
+		fresh = append(fresh, slotCoeffs(ct, dec, params, tau)...)
+
+		slots := RLWE.UnpackCt(ct, tau, tau, evalRLWE, ringQ, monomials, params)
+		for _, s := range slots {
+			slot = append(slot, slotCoeffs(s, dec, params, tau)...)
+		}
+
+		ctExt := rlwe.NewCiphertext(params, 1, params.MaxLevel())
+		evalRGSW.ExternalProduct(slots[0], zeroRGSW, ctExt)
+		ext = append(ext, slotCoeffs(ctExt, dec, params, tau)...)
+	}
+	return Noise{Fresh: rms(fresh), Slot: rms(slot), Ext: rms(ext)}
+}
+
+func rms(v []float64) float64 {
+	sum := 0.0
+	for _, a := range v {
+		sum += a * a
+	}
+	return math.Sqrt(sum / float64(len(v)))
+}
+
+// 복호화 결과 중 packing slot 계수 (N*i/tau, DecUnpack 이 읽는 위치) 를 [-q/2, q/2) 로
+func slotCoeffs(ct *rlwe.Ciphertext, dec *rlwe.Decryptor, params rlwe.Parameters, tau int) []float64 {
+	pt := dec.DecryptNew(ct)
+	if pt.IsNTT {
+		params.RingQ().INTT(pt.Value, pt.Value)
+	}
+	q := params.Q()[0]
+	out := make([]float64, tau)
+	for i := range out {
+		c := pt.Value.Coeffs[0][params.N()*i/tau]
+		out[i] = float64(c)
+		if c >= q/2 {
+			out[i] = -float64(q - c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,334 @@
+// 양자화 스케일 r, s, L 자동 선택
+//
+// keygen 과 같은 방식으로 정수화한 제어기를 float 제어기와 비교
+//
+//	Ḡ = G/s, H̄ = H/s, J̄ = J/s² (EncPack 과 같이 0 방향 절삭)
+//	ȳ = round(y/r), x̄ = round(x/(r s))
+//	ū = H̄ x̄ + J̄ ȳ,  u ≈ r s² ū,  암호문 plaintext = ū · int64(1/L) (EncPack 과 같이 절삭)
+//
+// 1/L 이 정수가 아닌 L 은 절삭으로 배율이 달라지므로 후보에서 제외 (1/L < 1 이면 평문이 0)
+//
+// 조건: |u_q - u| + noise ≤ TargetErr, plaintext + noise < Q/2 / 2^MarginBits
+// noise 는 스텝마다 독립이라 보고 분산을 누적, 상한은 NoiseSigmas·σ
+package quant
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"Encrypted_Cartpole/03_Utils/spec"
+)
+
+// 입력 조건
+type Problem struct {
+	F, G, H, J [][]float64
+	XIni       []float64
+
+	YMax       []float64 // |y_i| ≤ YMax[i]
+	Horizon    int       // 확인할 스텝 수 (적분 상태가 커지는 만큼)
+	TargetErr  float64   // 허용 |u_q - u|
+	LogQ       float64   // log2(Q), 첫 modulus
+	MarginBits float64   // Q/2 대비 최소 여유 (bit)
+	Noise      Noise     // MeasureNoise 결과 (0 이면 noise 무시)
+}
+
+// noise 상한으로 쓰는 표준편차 배수 (6σ 초과 확률 ≈ 2e-9)
+const NoiseSigmas = 6
+
+// 카트폴 기본값: y 는 plant 안전 한계 (angle 40, position 200, 넘으면 plant 가 u=0),
+// 300 스텝 동안 한계값이 유지되어도 |u_q - u| ≤ 1 (지금 손으로 정한 N12 스케일은 이 기준으로 ≈ 4)
+func DefaultProblem(sp *spec.Spec) Problem {
+	F, G, H, _, J := sp.Matrices()
+	return Problem{
+		F: F, G: G, H: H, J: J,
+		XIni:       sp.XIni,
+		YMax:       []float64{40, 200},
+		Horizon:    300,
+		TargetErr:  1,
+		LogQ:       float64(sp.Params.LogQ[0]),
+		MarginBits: 4,
+	}
+}
+
+// 평가 결과
+type Result struct {
+	Scales spec.Scales
+
+	QuantErr float64 // 정수화 오차 max |u_q - u|
+	NoiseErr float64 // noise 가 u 에 주는 오차 상한
+	TotalErr float64
+
+	MaxPlainBits float64 // log2(max |plaintext| + noise)
+	MarginBits   float64 // log2(Q/2) - MaxPlainBits
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("1/s=%.6g 1/r=%.6g 1/L=%.6g | err quant=%.3g noise=%.3g total=%.3g | plaintext 2^%.1f, margin %.1f bit",
+		1/r.Scales.S, 1/r.Scales.R, 1/r.Scales.L, r.QuantErr, r.NoiseErr, r.TotalErr, r.MaxPlainBits, r.MarginBits)
+}
+
+// 주어진 스케일 평가
+func Evaluate(pb Problem, sc spec.Scales) (Result, error) {
+	if err := pb.check(); err != nil {
+		return Result{}, err
+	}
+	if _, ok := plainScale(sc.L); !ok {
+		return Result{}, fmt.Errorf("L = %g: 1/L must be a positive integer (EncPack uses int64(1/L) = %d)", sc.L, int64(1/sc.L))
+	}
+	return pb.simulate(sc.S, sc.R).finish(pb, sc.L), nil
+}
+
+// EncPack 이 실제로 곱하는 배율 int64(1/L), 절삭으로 값이 바뀌거나 0 이면 ok=false
+func plainScale(L float64) (float64, bool) {
+	d := float64(int64(1 / L))
+	return d, d >= 1 && d == 1/L
+}
+
+func (pb Problem) check() error {
+	if len(pb.YMax) != len(pb.G[0]) {
+		return fmt.Errorf("YMax: want %d entries, got %d", len(pb.G[0]), len(pb.YMax))
+	}
+	for _, row := range pb.F {
+		for _, v := range row {
+			if v != math.Trunc(v) {
+				return errors.New("F must be an integer matrix (no re-encryption of the state)")
+			}
+		}
+	}
+	return nil
+}
+
+// (s, r) 에만 의존하는 값 (L 은 plaintext 크기와 noise 환산에만 들어감)
+type stats struct {
+	s, r     float64
+	quantErr float64 // max |u_q - u|
+	maxInt   float64 // max |ū|, |x̄|, |ȳ| (L 적용 전 정수)
+	eX, eU   float64 // noise 상한 (plaintext 정수 단위, NoiseSigmas·σ)
+}
+
+func (pb Problem) simulate(s, r float64) stats {
+	st := stats{s: s, r: r}
+	Fq := pb.F
+	Gq := truncMat(1/s, pb.G)
+	Hq := truncMat(1/s, pb.H)
+	Jq := truncMat(1/(s*s), pb.J)
+
+	// 정수화 오차 + 정수 크기: 여러 y 시퀀스로 시뮬레이션
+	for _, ys := range ySequences(pb.YMax, pb.Horizon) {
+		x := append([]float64(nil), pb.XIni...)
+		xq := roundVec(scalVec(1/(r*s), pb.XIni))
+		for _, y := range ys {
+			yq := roundVec(scalVec(1/r, y))
+
+			u := vecAdd(matVec(pb.H, x), matVec(pb.J, y))
+			uq := vecAdd(matVec(Hq, xq), matVec(Jq, yq))
+			for i := range u {
+				st.quantErr = math.Max(st.quantErr, math.Abs(uq[i]*r*s*s-u[i]))
+			}
+			st.maxInt = math.Max(st.maxInt, math.Max(maxAbs(uq), math.Max(maxAbs(xq), maxAbs(yq))))
+
+			x = vecAdd(matVec(pb.F, x), matVec(pb.G, y))
+			xq = vecAdd(matVec(Fq, xq), matVec(Gq, yq))
+		}
+	}
+
+	// noise 분산: 적분 상태에는 매 스텝 외부곱/unpack noise 가 더해짐
+	nz := pb.Noise
+	vSlot := nz.Slot * nz.Slot
+	vExt := float64(len(pb.F)+len(pb.G[0])) * nz.Ext * nz.Ext
+	vUnpack := math.Max(vSlot-nz.Fresh*nz.Fresh, 0)
+	vX, vU := vSlot, 0.0
+	for k := 0; k < pb.Horizon; k++ {
+		vU = math.Max(vU, rowSqMax(Hq)*vX+rowSqMax(Jq)*vSlot+vExt)
+		vX = rowSqMax(Fq)*vX + rowSqMax(Gq)*vSlot + vExt + vUnpack
+	}
+	st.eX = NoiseSigmas * math.Sqrt(vX)
+	st.eU = NoiseSigmas * math.Sqrt(vU)
+	return st
+}
+
+func (st stats) finish(pb Problem, L float64) Result {
+	res := Result{Scales: spec.Scales{S: st.s, L: L, R: st.r}}
+	delta, _ := plainScale(L)
+	res.QuantErr = st.quantErr
+	res.NoiseErr = st.eU * st.r * st.s * st.s / delta
+	res.TotalErr = res.QuantErr + res.NoiseErr
+	res.MaxPlainBits = math.Log2(st.maxInt*delta + math.Max(st.eU, st.eX) + 1)
+	res.MarginBits = (pb.LogQ - 1) - res.MaxPlainBits
+	return res
+}
+
+// 후보 스케일 (1, 2, 5 × 10^e, 10진수로 정확히 쓰이도록 나눗셈으로 계산)
+func candidates(minExp, maxExp int) []float64 {
+	out := []float64{}
+	for e := maxExp; e >= minExp; e-- {
+		for _, m := range []float64{5, 2, 1} {
+			if e < 0 {
+				out = append(out, m/math.Pow10(-e))
+			} else {
+				out = append(out, m*math.Pow10(e))
+			}
+		}
+	}
+	return out
+}
+
+// L 후보: 1/L = 1, 2, 5 × 10^e (L 이 큰 쪽부터), 1/L 이 float 로 정확하지 않은 것은 제외
+func lCandidates(maxExp int) []float64 {
+	out := []float64{}
+	for e, p := 0, int64(1); e <= maxExp; e, p = e+1, p*10 {
+		for _, m := range []int64{1, 2, 5} {
+			L := 1 / float64(m*p)
+			if _, ok := plainScale(L); ok {
+				out = append(out, L)
+			}
+		}
+	}
+	return out
+}
+
+// 조건을 만족하는 스케일 중 Q/2 여유가 가장 큰 것 선택 (여유가 같으면 오차가 작은 쪽)
+// 만족하는 스케일이 없으면 여유 조건 안에서 오차가 가장 작은 후보를 에러와 같이 반환
+func Select(pb Problem) (Result, error) {
+	if err := pb.check(); err != nil {
+		return Result{}, err
+	}
+	var best, closest Result
+	found, near := false, false
+	for _, s := range candidates(-4, 0) {
+		for _, r := range candidates(-7, -1) {
+			st := pb.simulate(s, r)
+			// L 이 클수록 plaintext 가 작아져 여유가 커지고 noise 오차는 커짐
+			for _, L := range lCandidates(9) {
+				res := st.finish(pb, L)
+				if res.MarginBits < pb.MarginBits {
+					continue
+				}
+				if !near || res.TotalErr < closest.TotalErr {
+					closest, near = res, true
+				}
+				if res.TotalErr > pb.TargetErr {
+					continue
+				}
+				if !found || res.MarginBits > best.MarginBits ||
+					(res.MarginBits == best.MarginBits && res.TotalErr < best.TotalErr) {
+					best, found = res, true
+				}
+				break
+			}
+		}
+	}
+	if !found {
+		err := fmt.Errorf("no (r, s, L) keeps the error below %g with %.1f bit margin under log Q = %.0f",
+			pb.TargetErr, pb.MarginBits, pb.LogQ)
+		if !near {
+			return Result{}, err
+		}
+		return closest, err
+	}
+	return best, nil
+}
+
+// 검사용 y 시퀀스: 부호 조합별 상수 (적분 상태 최대), 교대 부호, 고정 seed 랜덤
+func ySequences(yMax []float64, horizon int) [][][]float64 {
+	p := len(yMax)
+	out := [][][]float64{}
+	for mask := 0; mask < 1<<p; mask++ {
+		y := make([]float64, p)
+		for i := range y {
+			y[i] = yMax[i]
+			if mask&(1<<i) != 0 {
+				y[i] = -yMax[i]
+			}
+		}
+		seq := make([][]float64, horizon)
+		alt := make([][]float64, horizon)
+		for k := range seq {
+			seq[k] = y
+			alt[k] = scalVec(math.Pow(-1, float64(k)), y)
+		}
+		out = append(out, seq, alt)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for t := 0; t < 4; t++ {
+		seq := make([][]float64, horizon)
+		for k := range seq {
+			seq[k] = make([]float64, p)
+			for i := range seq[k] {
+				seq[k][i] = (2*rng.Float64() - 1) * yMax[i]
+			}
+		}
+		out = append(out, seq)
+	}
+	return out
+}
+
+// ===== 작은 행렬 헬퍼 (RGSW.EncPack 의 정수화와 동일하게 0 방향 절삭) =====
+
+func truncMat(a float64, M [][]float64) [][]float64 {
+	out := make([][]float64, len(M))
+	for i, row := range M {
+		out[i] = make([]float64, len(row))
+		for j, v := range row {
+			out[i][j] = math.Trunc(a * v)
+		}
+	}
+	return out
+}
+
+func matVec(M [][]float64, v []float64) []float64 {
+	out := make([]float64, len(M))
+	for i, row := range M {
+		for j, a := range row {
+			out[i] += a * v[j]
+		}
+	}
+	return out
+}
+
+func vecAdd(a, b []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range a {
+		out[i] = a[i] + b[i]
+	}
+	return out
+}
+
+func scalVec(a float64, v []float64) []float64 {
+	out := make([]float64, len(v))
+	for i := range v {
+		out[i] = a * v[i]
+	}
+	return out
+}
+
+func roundVec(v []float64) []float64 {
+	out := make([]float64, len(v))
+	for i := range v {
+		out[i] = math.Round(v[i])
+	}
+	return out
+}
+
+func maxAbs(v []float64) float64 {
+	m := 0.0
+	for _, a := range v {
+		m = math.Max(m, math.Abs(a))
+	}
+	return m
+}
+
+// 행별 제곱합의 최대 (독립 noise 의 분산 전파)
+func rowSqMax(M [][]float64) float64 {
+	m := 0.0
+	for _, row := range M {
+		sum := 0.0
+		for _, a := range row {
+			sum += a * a
+		}
+		m = math.Max(m, sum)
+	}
+	return m
+}
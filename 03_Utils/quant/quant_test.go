@@ -0,0 +1,42 @@
+package quant
+
+import (
+	"path/filepath"
+	"testing"
+
+	"Encrypted_Cartpole/03_Utils/spec"
+)
+
+// EncPack 은 int64(1/L) 을 곱하므로 고른 L 은 1/L 이 정확한 정수여야 함
+func TestSelectIntegerInvL(t *testing.T) {
+	for _, L := range lCandidates(9) {
+		if d, ok := plainScale(L); !ok || d != 1/L {
+			t.Fatalf("candidate L=%g: int64(1/L)=%d", L, int64(1/L))
+		}
+	}
+	sp, err := spec.Load(filepath.Join("..", "..", "config", "cartpole_N12.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Select(DefaultProblem(sp)) // noise 0
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := plainScale(res.Scales.L); !ok {
+		t.Fatalf("selected 1/L=%v is not an integer", 1/res.Scales.L)
+	}
+}
+
+func TestEvaluateRejectsInexactL(t *testing.T) {
+	sp, err := spec.Load(filepath.Join("..", "..", "config", "cartpole_N12.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, L := range []float64{5, 2e-5, 1e-5} {
+		sc := sp.Scales
+		sc.L = L
+		if _, err := Evaluate(DefaultProblem(sp), sc); err == nil {
+			t.Errorf("L=%g: want error (int64(1/L)=%d)", L, int64(1/L))
+		}
+	}
+}
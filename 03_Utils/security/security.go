@@ -0,0 +1,149 @@
+// RLWE 파라미터 보안 수준 추정 (Homomorphic Encryption Standard 표 기준)
+//
+// Albrecht et al., "Homomorphic Encryption Security Standard" (2018), Table 1~3
+// 고전(classical) 공격 기준, error 표준편차 σ ≈ 3.2 를 가정한 log(QP) 최대값
+package security
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+// 표가 가정하는 error 표준편차 (8/sqrt(2π))
+const StandardSigma = 3.19
+
+// 표에 있는 보안 수준 (bit)
+var Levels = []int{128, 192, 256}
+
+// logN → {128, 192, 256}-bit 에서 허용되는 최대 log(QP)
+var (
+	// secret: uniform mod q
+	tableUniform = map[int][3]int{
+		10: {29, 21, 16},
+		11: {56, 39, 31},
+		12: {111, 77, 60},
+		13: {220, 154, 120},
+		14: {440, 307, 239},
+		15: {883, 613, 478},
+	}
+	// secret: error 분포 (discrete Gaussian)
+	tableError = tableUniform
+	// secret: uniform ternary {-1, 0, 1}
+	tableTernary = map[int][3]int{
+		10: {27, 19, 14},
+		11: {54, 37, 29},
+		12: {109, 75, 58},
+		13: {218, 152, 118},
+		14: {438, 305, 237},
+		15: {881, 611, 476},
+	}
+)
+
+// 추정 결과
+type Report struct {
+	LogN   int
+	LogQP  float64
+	Secret string // ternary / gaussian / uniform
+
+	// 표에서 만족하는 가장 높은 수준 (128 미만이면 0)
+	Level int
+	// 표를 log(QP) 에 반비례로 보간한 대략적인 보안 bit 수
+	Estimate float64
+	// 128/192/256-bit 의 최대 log(QP)
+	MaxLogQP [3]int
+}
+
+func (r Report) Secure(minBits int) bool {
+	return r.Level >= minBits
+}
+
+func (r Report) String() string {
+	level := "< 128-bit"
+	if r.Level > 0 {
+		level = fmt.Sprintf(">= %d-bit", r.Level)
+	}
+	return fmt.Sprintf("N=2^%d, log(QP)=%.1f, secret=%s → %s (≈%.0f bit; max log(QP) for 128/192/256 = %d/%d/%d)",
+		r.LogN, r.LogQP, r.Secret, level, r.Estimate, r.MaxLogQP[0], r.MaxLogQP[1], r.MaxLogQP[2])
+}
+
+// 파라미터의 보안 수준 추정
+// 표에 없는 N, sparse ternary secret, σ < 3.19 error 는 추정하지 않고 에러
+func Estimate(params rlwe.Parameters) (Report, error) {
+	r := Report{LogN: params.LogN(), LogQP: params.LogQP()}
+
+	var table map[int][3]int
+	switch xs := params.Xs().(type) {
+	case ring.Ternary:
+		if xs.H != 0 {
+			return r, fmt.Errorf("sparse ternary secret (H=%d) is not covered by the HE standard tables", xs.H)
+		}
+		r.Secret = "ternary"
+		table = tableTernary
+	case ring.DiscreteGaussian:
+		r.Secret = "gaussian"
+		table = tableError
+	case ring.Uniform:
+		r.Secret = "uniform"
+		table = tableUniform
+	default:
+		return r, fmt.Errorf("unknown secret distribution %T", xs)
+	}
+
+	xe, ok := params.Xe().(ring.DiscreteGaussian)
+	if !ok {
+		return r, fmt.Errorf("error distribution %T is not covered by the HE standard tables", params.Xe())
+	}
+	if xe.Sigma < StandardSigma {
+		return r, fmt.Errorf("error sigma %.3f is below the %.2f assumed by the HE standard tables", xe.Sigma, StandardSigma)
+	}
+
+	bounds, ok := table[r.LogN]
+	if !ok {
+		logNs := make([]int, 0, len(table))
+		for k := range table {
+			logNs = append(logNs, k)
+		}
+		sort.Ints(logNs)
+		return r, fmt.Errorf("logN=%d is outside the HE standard tables (logN %d..%d)", r.LogN, logNs[0], logNs[len(logNs)-1])
+	}
+	r.MaxLogQP = bounds
+
+	for i, lv := range Levels {
+		if r.LogQP <= float64(bounds[i]) {
+			r.Level = lv
+		}
+	}
+
+	// 보안 bit 수는 대략 N/log(QP) 에 비례 → 128-bit 경계값 기준으로 비례 환산
+	r.Estimate = 128 * float64(bounds[0]) / r.LogQP
+	if r.Level > 0 {
+		// 표에 있는 수준보다 낮게 나오지 않도록
+		r.Estimate = math.Max(r.Estimate, float64(r.Level))
+	}
+	return r, nil
+}
+
+// literal 로 바로 추정
+func EstimateLiteral(lit rlwe.ParametersLiteral) (Report, error) {
+	params, err := rlwe.NewParametersFromLiteral(lit)
+	if err != nil {
+		return Report{}, err
+	}
+	return Estimate(params)
+}
+
+// minBits 를 만족하지 않으면 에러
+func Check(params rlwe.Parameters, minBits int) (Report, error) {
+	r, err := Estimate(params)
+	if err != nil {
+		return r, err
+	}
+	if !r.Secure(minBits) {
+		return r, fmt.Errorf("insecure parameters: %s, need >= %d-bit", r, minBits)
+	}
+	return r, nil
+}
@@ -0,0 +1,93 @@
+package security
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+func lit(logN int, logQ, logP []int) rlwe.ParametersLiteral {
+	return rlwe.ParametersLiteral{LogN: logN, LogQ: logQ, LogP: logP, NTTFlag: true}
+}
+
+// 배포 config 와 표 경계 근처 값 (lattigo 기본 secret = ternary, σ = 3.2)
+func TestEstimateTable(t *testing.T) {
+	cases := []struct {
+		name  string
+		lit   rlwe.ParametersLiteral
+		level int
+		max   [3]int
+	}{
+		{"cartpole_N10", lit(10, []int{56}, []int{51}), 0, [3]int{27, 19, 14}},     // log(QP)=107
+		{"cartpole_N11", lit(11, []int{28}, []int{28}), 0, [3]int{54, 37, 29}},     // 56 > 54
+		{"cartpole_test", lit(12, []int{40}, []int{40}), 128, [3]int{109, 75, 58}}, // 80
+		{"N12_QP72", lit(12, []int{36}, []int{36}), 192, [3]int{109, 75, 58}},
+		{"N13_QP100", lit(13, []int{50}, []int{50}), 256, [3]int{218, 152, 118}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := EstimateLiteral(tc.lit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Secret != "ternary" || r.Level != tc.level || r.MaxLogQP != tc.max {
+				t.Fatalf("got secret %s level %d max %v, want ternary %d %v", r.Secret, r.Level, r.MaxLogQP, tc.level, tc.max)
+			}
+			want := 128 * float64(tc.max[0]) / r.LogQP
+			if tc.level > 0 {
+				want = math.Max(want, float64(tc.level))
+			}
+			if r.Estimate != want {
+				t.Errorf("estimate %v, want %v", r.Estimate, want)
+			}
+			if r.Secure(128) != (tc.level >= 128) {
+				t.Errorf("Secure(128) = %v at level %d", r.Secure(128), tc.level)
+			}
+		})
+	}
+}
+
+// gaussian secret 은 uniform/error 표를 씀
+func TestEstimateGaussianSecret(t *testing.T) {
+	l := lit(12, []int{40}, []int{40})
+	l.Xs = ring.DiscreteGaussian{Sigma: 3.2, Bound: 19.2}
+	r, err := EstimateLiteral(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Secret != "gaussian" || r.Level != 128 || r.MaxLogQP != [3]int{111, 77, 60} {
+		t.Fatalf("got %+v", r)
+	}
+}
+
+func TestEstimateUncovered(t *testing.T) {
+	sparse := lit(12, []int{40}, []int{40})
+	sparse.Xs = ring.Ternary{H: 64}
+	narrow := lit(12, []int{40}, []int{40})
+	narrow.Xe = ring.DiscreteGaussian{Sigma: 2, Bound: 12}
+	for name, l := range map[string]rlwe.ParametersLiteral{
+		"logN16":       lit(16, []int{60}, []int{60}),
+		"sparse":       sparse,
+		"sigma below":  narrow,
+		"logN9 (tiny)": lit(9, []int{20}, []int{20}),
+	} {
+		if r, err := EstimateLiteral(l); err == nil {
+			t.Errorf("%s: estimated %s, want error", name, r)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	params, err := rlwe.NewParametersFromLiteral(lit(12, []int{40}, []int{40}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Check(params, 128); err != nil {
+		t.Errorf("128-bit: %v", err)
+	}
+	if _, err := Check(params, 192); err == nil {
+		t.Error("192-bit accepted at log(QP)=80")
+	}
+}
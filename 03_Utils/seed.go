@@ -0,0 +1,111 @@
+package com_utils
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/tuneinsight/lattigo/v6/core/rgsw"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+	"github.com/tuneinsight/lattigo/v6/ring/ringqp"
+	"github.com/tuneinsight/lattigo/v6/utils/sampling"
+)
+
+// 재현 가능한 테스트용 결정적 모드 (실제 운용 금지)
+//
+// keygen 의 KeyGenerator / Encryptor 와 plant 의 y 암호화에만 seed 로 키를 건 blake2b XOF 를 넣음
+// crypto/rand.Reader 는 그대로 → TLS 키 / handshake, sk 봉인의 salt / nonce 는 계속 무작위
+// 용도 (label) 마다 PRNG 를 따로 만들어서 객체끼리 PRNG 를 공유하지 않음 (KeyedPRNG 는 goroutine 안전하지 않음)
+// 같은 seed + 같은 spec 이면 sk, 평가키, RGSW pack, xCtPack, y 암호문 열이 바이트 단위로 같음 (seed_test.go)
+//
+// seed 를 아는 사람은 비밀키를 다시 만들 수 있으므로 골든 파일 / 회귀 비교 외에는 쓰면 안 됨
+const UnsafeSeedWarning = "[UNSAFE] deterministic seeded randomness: keys and ciphertexts are reproducible from the seed — never use for real runs"
+
+// seed 도메인 분리용 접두사 (blake2b 키는 64바이트 제한이라 SHA-256 으로 줄여서 사용)
+const unsafeSeedDomain = "Encrypted_Cartpole/unsafe-seed/v1:"
+
+// -unsafe-seed 값 (nil 이면 보통의 무작위 객체를 돌려줌)
+type UnsafeSeed struct {
+	seed string
+}
+
+func NewUnsafeSeed(seed string) (*UnsafeSeed, error) {
+	if seed == "" {
+		return nil, errors.New("unsafe seed: empty seed")
+	}
+	return &UnsafeSeed{seed: seed}, nil
+}
+
+// seed + label 로 키를 건 PRNG (label 이 다르면 독립된 스트림, 한 goroutine 에서만)
+func (u *UnsafeSeed) PRNG(label string) *sampling.KeyedPRNG {
+	key := sha256.Sum256([]byte(unsafeSeedDomain + u.seed + "/" + label))
+	prng, err := sampling.NewKeyedPRNG(key[:])
+	if err != nil {
+		// blake2b 키 길이 (32) 는 항상 유효
+		panic(err)
+	}
+	return prng
+}
+
+// 같은 seed 에서 갈라진 독립 seed (noise 측정, 시뮬레이션처럼 key 와 섞이면 안 되는 용도, nil 이면 nil)
+func (u *UnsafeSeed) Sub(label string) *UnsafeSeed {
+	if u == nil {
+		return nil
+	}
+	return &UnsafeSeed{seed: u.seed + "/" + label}
+}
+
+func (u *UnsafeSeed) KeyGenerator(params rlwe.ParameterProvider) *rlwe.KeyGenerator {
+	kgen := rlwe.NewKeyGenerator(params)
+	if u != nil {
+		seedEncryptor(kgen.Encryptor, u.PRNG("keygen"))
+	}
+	return kgen
+}
+
+func (u *UnsafeSeed) Encryptor(params rlwe.ParameterProvider, sk *rlwe.SecretKey) *rlwe.Encryptor {
+	enc := rlwe.NewEncryptor(params, sk)
+	if u != nil {
+		seedEncryptor(enc, u.PRNG("rlwe"))
+	}
+	return enc
+}
+
+func (u *UnsafeSeed) RGSWEncryptor(params rlwe.ParameterProvider, sk *rlwe.SecretKey) *rgsw.Encryptor {
+	enc := rgsw.NewEncryptor(params, sk)
+	if u != nil {
+		seedEncryptor(enc.Encryptor, u.PRNG("rgsw"))
+	}
+	return enc
+}
+
+// lattigo Encryptor 의 sampler 는 생성할 때 crypto/rand 로 키를 건 PRNG 하나에서 나오고 바꾸는 API 가 없음
+// (WithPRNG 는 uniform sampler 만 바꿈) → 필드 이름으로 prng, xs / xe / uniform sampler 를 prng 기준으로 교체
+// lattigo 버전이 바뀌어 필드가 없으면 조용히 무작위로 남지 않도록 panic
+func seedEncryptor(enc *rlwe.Encryptor, prng sampling.PRNG) {
+	params := enc.GetRLWEParameters()
+	xe, err := ring.NewSampler(prng, params.RingQ(), params.Xe(), false)
+	if err != nil {
+		panic(fmt.Errorf("unsafe seed: %w", err))
+	}
+	xs, err := ring.NewSampler(prng, params.RingQ(), params.Xs(), false)
+	if err != nil {
+		panic(fmt.Errorf("unsafe seed: %w", err))
+	}
+	v := reflect.ValueOf(enc).Elem()
+	for name, x := range map[string]any{
+		"prng":           prng,
+		"xeSampler":      xe,
+		"xsSampler":      xs,
+		"uniformSampler": ringqp.NewUniformSampler(prng, *params.RingQP()),
+	} {
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			panic(fmt.Sprintf("unsafe seed: rlwe.Encryptor has no field %q (lattigo version changed)", name))
+		}
+		reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Set(reflect.ValueOf(x))
+	}
+}
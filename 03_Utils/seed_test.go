@@ -0,0 +1,117 @@
+package com_utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"Encrypted_Cartpole/03_Utils/spec"
+
+	utils "github.com/CDSL-EncryptedControl/CDSL/utils"
+	RGSW "github.com/CDSL-EncryptedControl/CDSL/utils/core/RGSW"
+	RLWE "github.com/CDSL-EncryptedControl/CDSL/utils/core/RLWE"
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+func loadTestSpec(t testing.TB, name string) (*spec.Spec, rlwe.Parameters) {
+	t.Helper()
+	sp, err := spec.Load(filepath.Join("..", "config", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := sp.RLWEParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sp, params
+}
+
+type binaryMarshaler interface {
+	MarshalBinary() ([]byte, error)
+}
+
+// keygen.go 와 같은 순서로 sk, 평가키, RGSW pack, xCtPack, seed 압축 y 를 만들어 직렬화
+func seededArtifacts(t *testing.T, sp *spec.Spec, params rlwe.Parameters, seed string) [][]byte {
+	t.Helper()
+	u, err := NewUnsafeSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tau := sp.Tau()
+	_, galEls := PackSetup(params, tau)
+
+	kgen := u.KeyGenerator(params)
+	sk := kgen.GenSecretKeyNew()
+	rlk := kgen.GenRelinearizationKeyNew(sk)
+	gks := kgen.GenGaloisKeysNew(galEls, sk)
+	encRLWE := u.Encryptor(params, sk)
+	encRGSW := u.RGSWEncryptor(params, sk)
+
+	F, G, _, _, _ := sp.Matrices()
+	ctF := RGSW.EncPack(F, tau, encRGSW, params.MaxLevelQ(), params.MaxLevelP(), params.RingQ(), params)
+	_, ctFG := EncSlotPack(F, G, encRGSW, params.MaxLevelQ(), params.MaxLevelP(), params)
+	xBar := utils.RoundVec(utils.ScalVecMult(1/(sp.Scales.R*sp.Scales.S), sp.XIni))
+	xCt := RLWE.EncPack(xBar, tau, 1/sp.Scales.L, *encRLWE, params.RingQ(), params)
+
+	codec := NewPlantCodec(params, tau, 1, sp.Scales, sk)
+	if err := codec.UseUnsafeSeed(u); err != nil {
+		t.Fatal(err)
+	}
+	yCt, err := codec.EncryptYSeeded([]float64{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objs := []binaryMarshaler{sk, rlk, xCt, codec.EncryptY([]float64{-0.25}), yCt.B}
+	for _, gk := range gks {
+		objs = append(objs, gk)
+	}
+	for _, ct := range append(ctF, ctFG...) {
+		objs = append(objs, ct)
+	}
+	out := [][]byte{yCt.Seed[:]}
+	for _, o := range objs {
+		b, err := o.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func TestUnsafeSeedByteIdentical(t *testing.T) {
+	sp, params := loadTestSpec(t, "cartpole_N10.json")
+	reader := rand.Reader
+
+	a := seededArtifacts(t, sp, params, "golden")
+	b := seededArtifacts(t, sp, params, "golden")
+	if len(a) != len(b) {
+		t.Fatalf("artifact count %d != %d", len(a), len(b))
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			t.Fatalf("artifact %d differs between two runs with the same seed", i)
+		}
+	}
+
+	c := seededArtifacts(t, sp, params, "other")
+	if bytes.Equal(a[1], c[1]) {
+		t.Fatal("different seeds gave the same secret key")
+	}
+	if rand.Reader != reader {
+		t.Fatal("crypto/rand.Reader was replaced")
+	}
+}
+
+// seed 가 없으면 보통의 무작위 (두 번 만든 sk 가 달라야 함)
+func TestNilUnsafeSeedRandom(t *testing.T) {
+	_, params := loadTestSpec(t, "cartpole_N10.json")
+	var u *UnsafeSeed
+	a, _ := u.KeyGenerator(params).GenSecretKeyNew().MarshalBinary()
+	b, _ := u.KeyGenerator(params).GenSecretKeyNew().MarshalBinary()
+	if bytes.Equal(a, b) {
+		t.Fatal("nil seed gave identical secret keys")
+	}
+}
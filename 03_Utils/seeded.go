@@ -0,0 +1,169 @@
+package com_utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+	"github.com/tuneinsight/lattigo/v6/ring"
+	"golang.org/x/crypto/sha3"
+)
+
+// seed 압축 y 암호문 (plant → controller 전송용)
+//
+// 비밀키 RLWE 암호문 (b, a) = (-a·s + e + m, a) 에서 a 는 균등분포라 짧은 seed 로 다시 만들 수 있음
+// plant 는 seed 에서 a 를 뽑아 암호화하고 (seed, b) 만 보냄 → N=2^12 에서 약 64 KB → 32 KB
+// controller 는 Expand 로 같은 a 를 다시 만들어 보통 rlwe.Ciphertext 로 복원한 뒤 UnpackCt
+//
+// 전송 형식: seed(32) | b (degree 0 rlwe.Ciphertext, 메타데이터 포함)
+// a = SHAKE128(seedDomain | seed) 의 rejection sampling (seedExpander), 프로토콜 v2 부터
+// (v1 은 lattigo KeyedPRNG + UniformSampler 였는데 PRNG 를 seed 마다 새로 잡아야 해서 스텝마다 할당)
+type SeededCiphertext struct {
+	Seed [SeedSize]byte
+	B    *rlwe.Ciphertext // Value[0] = b 만 있음
+
+	wire elemWire
+	exp  *seedExpander // ExpandInto 용 (처음 한 번 잡음)
+}
+
+// a 를 만드는 seed 크기
+const SeedSize = 32
+
+var seedDomain = []byte("CPEC seeded y a, v2")
+
+// seed → NTT 영역 균등 다항식 a (SHAKE 상태와 읽기 버퍼를 재사용 → 할당 없음)
+// 계수마다 8 바이트 (little endian) 를 q 의 비트 수로 자르고 q 이상이면 버림, 모듈러스가 여럿이면 Q 순서대로 이어서
+// lattigo encryptZeroSk 처럼 뽑은 값을 그대로 NTT 영역 값으로 씀 (균등분포는 NTT 여부와 상관없음)
+type seedExpander struct {
+	xof    sha3.ShakeHash
+	buf    [8 * 168]byte // SHAKE128 rate 의 배수
+	moduli []uint64
+	masks  []uint64
+}
+
+func newSeedExpander(ringQ *ring.Ring) *seedExpander {
+	e := &seedExpander{xof: sha3.NewShake128()}
+	for _, s := range ringQ.SubRings {
+		e.moduli = append(e.moduli, s.Modulus)
+		e.masks = append(e.masks, 1<<bits.Len64(s.Modulus)-1)
+	}
+	return e
+}
+
+func (e *seedExpander) read(seed []byte, a ring.Poly) {
+	e.xof.Reset()
+	e.xof.Write(seedDomain)
+	e.xof.Write(seed)
+	pos := len(e.buf)
+	for i, c := range a.Coeffs {
+		q, mask := e.moduli[i], e.masks[i]
+		for j := 0; j < len(c); {
+			if pos == len(e.buf) {
+				e.xof.Read(e.buf[:])
+				pos = 0
+			}
+			v := binary.LittleEndian.Uint64(e.buf[pos:]) & mask
+			pos += 8
+			if v < q {
+				c[j] = v
+				j++
+			}
+		}
+	}
+}
+
+// a = seed 로 다시 뽑은 균등 다항식 (새 암호문, b 는 sc.B 와 공유)
+func (sc *SeededCiphertext) Expand(params rlwe.Parameters) (*rlwe.Ciphertext, error) {
+	if sc.B == nil || sc.B.Degree() != 0 {
+		return nil, fmt.Errorf("seeded ct: want b only (degree 0)")
+	}
+	a := params.RingQ().AtLevel(sc.B.Level()).NewPoly()
+	newSeedExpander(params.RingQ()).read(sc.Seed[:], a)
+	return &rlwe.Ciphertext{Element: rlwe.Element[ring.Poly]{
+		Value:    []ring.Poly{sc.B.Value[0], a},
+		MetaData: sc.B.MetaData,
+	}}, nil
+}
+
+// 받은 y 가 params 의 ring degree N, level 에 맞는 NTT 암호문인지 (Decode 직후, 안 맞으면 ExpandInto / UnpackCt 가 panic)
+func (sc *SeededCiphertext) Check(params rlwe.Parameters, level int) error {
+	if sc.B == nil || sc.B.Degree() != 0 || sc.B.MetaData == nil {
+		return fmt.Errorf("seeded ct: want b only (degree 0) with metadata")
+	}
+	if n := sc.B.Value[0].N(); n != params.N() {
+		return fmt.Errorf("seeded ct: ring degree %d, want %d", n, params.N())
+	}
+	if l := sc.B.Level(); l != level {
+		return fmt.Errorf("seeded ct: level %d, want %d", l, level)
+	}
+	if !sc.B.IsNTT {
+		return fmt.Errorf("seeded ct: b is not in the NTT domain")
+	}
+	return nil
+}
+
+// Expand 와 같지만 미리 잡은 degree 1 암호문에 (b 복사, a 는 ct.Value[1] 에) → 스텝마다 할당 없음
+func (sc *SeededCiphertext) ExpandInto(params rlwe.Parameters, ct *rlwe.Ciphertext) error {
+	if sc.B == nil || sc.B.Degree() != 0 {
+		return fmt.Errorf("seeded ct: want b only (degree 0)")
+	}
+	if ct.Degree() != 1 || ct.Level() != sc.B.Level() {
+		return fmt.Errorf("seeded ct: b at level %d does not fit a degree %d ciphertext at level %d", sc.B.Level(), ct.Degree(), ct.Level())
+	}
+	if sc.exp == nil {
+		sc.exp = newSeedExpander(params.RingQ())
+	}
+	ct.Value[0].Copy(sc.B.Value[0])
+	sc.exp.read(sc.Seed[:], ct.Value[1])
+	*ct.MetaData = *sc.B.MetaData
+	return nil
+}
+
+func (sc *SeededCiphertext) BinarySize() int {
+	return SeedSize + sc.B.BinarySize()
+}
+
+func (sc *SeededCiphertext) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(sc.Seed[:])
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := sc.wire.writeTo(w, sc.B)
+	return int64(n) + m, err
+}
+
+// Frame.Decode 가 쓰는 경로 (같은 sc 로 계속 읽으면 B 를 재사용 → 할당 없음)
+func (sc *SeededCiphertext) UnmarshalBinary(p []byte) error {
+	if len(p) < SeedSize {
+		return fmt.Errorf("seeded ct: %d bytes, shorter than the seed", len(p))
+	}
+	copy(sc.Seed[:], p)
+	if err := sc.wire.unmarshal(p[SeedSize:], &sc.B); err != nil {
+		return err
+	}
+	if sc.B.Degree() != 0 {
+		return fmt.Errorf("seeded ct: want b only (degree 0), got degree %d", sc.B.Degree())
+	}
+	return nil
+}
+
+func (sc *SeededCiphertext) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.ReadFull(r, sc.Seed[:])
+	if err != nil {
+		return int64(n), err
+	}
+	if sc.B == nil {
+		sc.B = new(rlwe.Ciphertext)
+	}
+	sc.wire.meta = nil
+	m, err := sc.B.ReadFrom(r)
+	if err != nil {
+		return int64(n) + m, err
+	}
+	if sc.B.Degree() != 0 {
+		return int64(n) + m, fmt.Errorf("seeded ct: want b only (degree 0), got degree %d", sc.B.Degree())
+	}
+	return int64(n) + m, nil
+}
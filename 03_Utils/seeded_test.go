@@ -0,0 +1,141 @@
+package com_utils
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// 다른 파라미터로 만든 y 는 Check 에서 걸러짐 (controller 가 SendError 로 버림)
+func TestSeededCheck(t *testing.T) {
+	sp, params := loadTestSpec(t, "cartpole_N12.json")
+	_, small := loadTestSpec(t, "cartpole_N10.json")
+	level := params.MaxLevel()
+
+	y, err := NewPlantCodec(params, sp.Tau(), 1, sp.Scales, rlwe.NewKeyGenerator(params).GenSecretKeyNew()).EncryptYSeeded([]float64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := y.Check(params, level); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.Check(small, level); err == nil {
+		t.Error("N=2^12 y accepted by N=2^10 parameters")
+	}
+	if err := y.Check(params, level+1); err == nil {
+		t.Error("wrong level accepted")
+	}
+	y.B.IsNTT = false
+	if err := y.Check(params, level); err == nil {
+		t.Error("non-NTT b accepted")
+	}
+}
+
+// 복호화한 계수 (centered, mod q)
+func decryptCentered(params rlwe.Parameters, dec *rlwe.Decryptor, ct *rlwe.Ciphertext) []int64 {
+	pt := dec.DecryptNew(ct)
+	if pt.IsNTT {
+		params.RingQ().INTT(pt.Value, pt.Value)
+	}
+	q := params.Q()[0]
+	out := make([]int64, params.N())
+	for i, v := range pt.Value.Coeffs[0] {
+		if v > q/2 {
+			out[i] = int64(v) - int64(q)
+		} else {
+			out[i] = int64(v)
+		}
+	}
+	return out
+}
+
+// seed 압축 y (손으로 쓴 b = -a·s + e + m, SHAKE128 a) 를 복원해 복호화하면 lattigo EncPack (EncryptY) 과 같은 평문
+func TestSeededDecryptsLikeEncPack(t *testing.T) {
+	const noiseBound = 64 // fresh 암호문 두 개의 잡음 차 (σ = 3.2)
+	for _, name := range []string{"cartpole_N10.json", "cartpole_N12.json"} {
+		sp, params := loadTestSpec(t, name)
+		tau := sp.Tau()
+		sk := rlwe.NewKeyGenerator(params).GenSecretKeyNew()
+		dec := rlwe.NewDecryptor(params, sk)
+		codec := NewPlantCodec(params, tau, 1, sp.Scales, sk)
+
+		y := make([]float64, tau)
+		for i := range y {
+			y[i] = float64(i+1) * 0.37 * float64(1-2*(i%2))
+		}
+		want := decryptCentered(params, dec, codec.EncryptY(y))
+
+		sc, err := codec.EncryptYSeeded(y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, err := sc.Expand(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := decryptCentered(params, dec, ct)
+		for i := range got {
+			if d := got[i] - want[i]; d > noiseBound || d < -noiseBound {
+				t.Fatalf("%s: coefficient %d: seeded %d, EncPack %d", name, i, got[i], want[i])
+			}
+		}
+		// slot 값 자체도 round(y/r)·(1/L)
+		scale := int64(1 / sp.Scales.L)
+		for r, v := range y {
+			m := scale * int64(math.Round(v/sp.Scales.R))
+			if d := got[params.N()*r/tau] - m; d > noiseBound || d < -noiseBound {
+				t.Fatalf("%s: slot %d: got %d, want %d", name, r, got[params.N()*r/tau], m)
+			}
+		}
+	}
+}
+
+// WriteTo → UnmarshalBinary / ReadFrom → ExpandInto 가 보낸 쪽 Expand 와 비트 단위로 같음 (받는 버퍼 재사용 포함)
+func TestSeededRoundTrip(t *testing.T) {
+	sp, params := loadTestSpec(t, "cartpole_N10.json")
+	codec := NewPlantCodec(params, sp.Tau(), 1, sp.Scales, rlwe.NewKeyGenerator(params).GenSecretKeyNew())
+
+	var recv SeededCiphertext
+	out := rlwe.NewCiphertext(params, 1, params.MaxLevel())
+	for step, y := range [][]float64{{0.5}, {-1.25}, {3}} {
+		sc, err := codec.EncryptYSeeded(y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := sc.Expand(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if _, err := sc.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		if b.Len() != sc.BinarySize() {
+			t.Fatalf("wrote %d bytes, BinarySize %d", b.Len(), sc.BinarySize())
+		}
+
+		if err := recv.UnmarshalBinary(b.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		if err := recv.ExpandInto(params, out); err != nil {
+			t.Fatal(err)
+		}
+		if !sameValue(out, want) || !out.MetaData.Equal(want.MetaData) {
+			t.Fatalf("step %d: UnmarshalBinary + ExpandInto differs from Expand", step)
+		}
+
+		var fresh SeededCiphertext
+		if _, err := fresh.ReadFrom(bytes.NewReader(b.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+		ct, err := fresh.Expand(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameValue(ct, want) {
+			t.Fatalf("step %d: ReadFrom + Expand differs", step)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// 제어기 정의(spec) 파일: offline keygen, controller, plant 가 모두 같은 파일을 읽음
+package spec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"Encrypted_Cartpole/03_Utils/pid"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// artifact 디렉토리에 같이 저장되는 spec 사본 이름
+const FileName = "spec.json"
+
+// RLWE 파라미터 (rlwe.ParametersLiteral 중 실제로 바꾸는 부분만)
+type Params struct {
+	LogN int   `json:"logN"`
+	LogQ []int `json:"logQ"`
+	LogP []int `json:"logP"`
+}
+
+// 출력 2개(angle, position)에 대한 병렬 PID 계수
+type PID struct {
+	Kp float64 `json:"kp"`
+	Ki float64 `json:"ki"`
+	Kd float64 `json:"kd"`
+
+	Lp float64 `json:"lp"`
+	Li float64 `json:"li"`
+	Ld float64 `json:"ld"`
+}
+
+// 양자화 스케일 (s: 제어기 행렬, L: 암호화, r: 출력/상태)
+type Scales struct {
+	S float64 `json:"s"`
+	L float64 `json:"L"`
+	R float64 `json:"r"`
+}
+
+type Spec struct {
+	Name   string    `json:"name"`
+	Params Params    `json:"params"`
+	PID    PID       `json:"pid"`
+	Scales Scales    `json:"scales"`
+	XIni   []float64 `json:"xIni"`
+}
+
+// spec 파일 로드 + 검증
+func Load(path string) (*Spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	sp := new(Spec)
+	if err := dec.Decode(sp); err != nil {
+		return nil, fmt.Errorf("parse spec %s: %w", path, err)
+	}
+	if err := sp.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid spec %s: %w", path, err)
+	}
+	return sp, nil
+}
+
+// spec 저장 (들여쓰기 JSON)
+func Save(path string, sp *Spec) error {
+	b, err := json.MarshalIndent(sp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func (sp *Spec) Validate() error {
+	if sp.Params.LogN <= 0 || len(sp.Params.LogQ) == 0 {
+		return errors.New("params: logN and logQ are required")
+	}
+	if sp.Scales.S <= 0 || sp.Scales.L <= 0 || sp.Scales.R <= 0 {
+		return errors.New("scales: s, L and r must be positive")
+	}
+	if inv := 1 / sp.Scales.L; float64(int64(inv)) != inv {
+		return fmt.Errorf("scales: 1/L = %v is not an integer (EncPack encodes with int64(1/L))", inv)
+	}
+	n, _, _ := sp.Dims()
+	if len(sp.XIni) != n {
+		return fmt.Errorf("xIni: want %d entries, got %d", n, len(sp.XIni))
+	}
+	return nil
+}
+
+// rlwe 파라미터 literal (NTTFlag 는 항상 true 로 사용)
+func (p Params) Literal() rlwe.ParametersLiteral {
+	return rlwe.ParametersLiteral{
+		LogN:    p.LogN,
+		LogQ:    append([]int(nil), p.LogQ...),
+		LogP:    append([]int(nil), p.LogP...),
+		NTTFlag: true,
+	}
+}
+
+// 커맨드라인 값으로 파라미터 덮어쓰기 (logN=0, 빈 문자열이면 그대로)
+// logQ, logP 는 "56" 또는 "40,40" 형식
+func (p *Params) Override(logN int, logQ, logP string) error {
+	if logN > 0 {
+		p.LogN = logN
+	}
+	for _, o := range []struct {
+		str string
+		dst *[]int
+	}{{logQ, &p.LogQ}, {logP, &p.LogP}} {
+		if o.str == "" {
+			continue
+		}
+		v, err := ParseInts(o.str)
+		if err != nil {
+			return err
+		}
+		*o.dst = v
+	}
+	return nil
+}
+
+// "56,51" → []int{56, 51}
+func ParseInts(str string) ([]int, error) {
+	out := []int{}
+	for _, f := range strings.Split(str, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, fmt.Errorf("bad int list %q: %w", str, err)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (sp *Spec) RLWEParams() (rlwe.Parameters, error) {
+	return rlwe.NewParametersFromLiteral(sp.Params.Literal())
+}
+
+// 출력 2개 (angle, position) 에 대한 PID 채널 (계수는 샘플 단위)
+func (sp *Spec) Channels() []pid.Channel {
+	k := sp.PID
+	return []pid.Channel{
+		{Kp: k.Kp, Ki: k.Ki, Kd: k.Kd},
+		{Kp: k.Lp, Ki: k.Li, Kd: k.Ld},
+	}
+}
+
+// PID 상태공간 realization (pid.Build, Ts=1)
+// x[k+1] = F x[k] + G y[k]
+// u[k]   = H x[k] + J y[k]
+// 상태행렬 F = diag(1,0,1,0) 이라 재암호화가 필요 없음
+func (sp *Spec) StateSpace() *pid.StateSpace {
+	ss, err := pid.Build(1, sp.Channels()...)
+	if err != nil {
+		// Ts=1, 필터 없는 채널이라 실패하지 않음
+		panic(err)
+	}
+	return ss
+}
+
+// keygen/controller 가 쓰는 행렬 (R 은 재암호화 행렬, 이 realization 에서는 0)
+func (sp *Spec) Matrices() (F, G, H, R, J [][]float64) {
+	ss := sp.StateSpace()
+	m, p := len(ss.H), len(ss.G[0])
+	R = make([][]float64, m)
+	for i := range R {
+		R[i] = make([]float64, p)
+	}
+	return ss.F, ss.G, ss.H, R, ss.J
+}
+
+// 상태 n, 입력 m, 출력 p 차원
+func (sp *Spec) Dims() (n, m, p int) {
+	return sp.StateSpace().Dims()
+}
+
+// packing slot 수 (max(n,m,p) 이상인 2의 거듭제곱)
+func (sp *Spec) Tau() int {
+	n, m, p := sp.Dims()
+	maxDim := math.Max(math.Max(float64(n), float64(m)), float64(p))
+	return int(math.Pow(2, math.Ceil(math.Log2(maxDim))))
+}
+
+// spec 내용의 SHA-256 (이름/필드 순서가 고정된 JSON 기준)
+func (sp *Spec) Fingerprint() string {
+	b, _ := json.Marshal(sp)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// artifact 를 만들 때 사용한 spec 과 지금 spec 이 같은지 확인
+func CheckArtifacts(dir string, sp *Spec) error {
+	built, err := Load(filepath.Join(dir, FileName))
+	if err != nil {
+		return fmt.Errorf("artifacts in %s have no usable %s: %w", dir, FileName, err)
+	}
+	if built.Fingerprint() == sp.Fingerprint() {
+		return nil
+	}
+	return fmt.Errorf("spec mismatch with artifacts in %s: %v", dir, Diff(built, sp))
+}
+
+// 두 spec 에서 다른 항목 이름 목록
+func Diff(a, b *Spec) []string {
+	out := []string{}
+	same := func(x, y interface{}) bool {
+		bx, _ := json.Marshal(x)
+		by, _ := json.Marshal(y)
+		return bytes.Equal(bx, by)
+	}
+	if a.Name != b.Name {
+		out = append(out, "name")
+	}
+	if !same(a.Params, b.Params) {
+		out = append(out, "params")
+	}
+	if !same(a.PID, b.PID) {
+		out = append(out, "pid")
+	}
+	if !same(a.Scales, b.Scales) {
+		out = append(out, "scales")
+	}
+	if !same(a.XIni, b.XIni) {
+		out = append(out, "xIni")
+	}
+	return out
+}
@@ -0,0 +1,143 @@
+package spec
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadConfig(t *testing.T, name string) *Spec {
+	t.Helper()
+	sp, err := Load(filepath.Join("..", "..", "config", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sp
+}
+
+// 배포 config 는 모두 로드되고 PID 4 상태 / 입력 1 / 출력 2
+func TestShippedConfigs(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "config", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no configs: %v", err)
+	}
+	for _, p := range paths {
+		sp := loadConfig(t, filepath.Base(p))
+		if n, m, q := sp.Dims(); n != 4 || m != 1 || q != 2 {
+			t.Errorf("%s: dims (%d,%d,%d), want (4,1,2)", p, n, m, q)
+		}
+		if sp.Tau() != 4 {
+			t.Errorf("%s: tau %d, want 4", p, sp.Tau())
+		}
+		if _, err := sp.RLWEParams(); err != nil {
+			t.Errorf("%s: %v", p, err)
+		}
+		// 예전에 손으로 쓴 H, J
+		k := sp.PID
+		_, _, H, R, J := sp.Matrices()
+		if got, want := H[0], []float64{k.Ki, -k.Kd, k.Li, -k.Ld}; !sameVec(got, want) {
+			t.Errorf("%s: H = %v, want %v", p, got, want)
+		}
+		if got, want := J[0], []float64{k.Kp + k.Ki + k.Kd, k.Lp + k.Li + k.Ld}; !sameVec(got, want) {
+			t.Errorf("%s: J = %v, want %v", p, got, want)
+		}
+		if !sameVec(R[0], []float64{0, 0}) {
+			t.Errorf("%s: R = %v, want 0", p, R)
+		}
+	}
+}
+
+// EncPack 은 int64(1/L) 로 인코딩하므로 1/L 이 정수가 아니면 거부
+func TestValidateInvL(t *testing.T) {
+	for _, tc := range []struct {
+		L  float64
+		ok bool
+	}{
+		{0.0001, true},
+		{0.001, true},
+		{0.5, true},
+		{0.0003, false},
+		{0.3, false},
+		{2.5e-5, true},
+	} {
+		sp := loadConfig(t, "cartpole_N10.json")
+		sp.Scales.L = tc.L
+		err := sp.Validate()
+		if (err == nil) != tc.ok {
+			t.Errorf("L=%g: err %v, want ok=%v", tc.L, err, tc.ok)
+		}
+		if err != nil && !strings.Contains(err.Error(), "1/L") {
+			t.Errorf("L=%g: unexpected error %v", tc.L, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, mod := range map[string]func(*Spec){
+		"no logN":    func(sp *Spec) { sp.Params.LogN = 0 },
+		"no logQ":    func(sp *Spec) { sp.Params.LogQ = nil },
+		"zero s":     func(sp *Spec) { sp.Scales.S = 0 },
+		"short xIni": func(sp *Spec) { sp.XIni = sp.XIni[:3] },
+	} {
+		sp := loadConfig(t, "cartpole_N10.json")
+		mod(sp)
+		if err := sp.Validate(); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+// Fingerprint 는 저장 / 다시 로드해도 같고, 내용이 바뀌면 달라짐
+func TestFingerprint(t *testing.T) {
+	sp := loadConfig(t, "cartpole_N12.json")
+	fp := sp.Fingerprint()
+	if fp != loadConfig(t, "cartpole_N12.json").Fingerprint() {
+		t.Fatal("fingerprint differs between loads")
+	}
+
+	dir := t.TempDir()
+	if err := Save(filepath.Join(dir, FileName), sp); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckArtifacts(dir, sp); err != nil {
+		t.Fatalf("saved copy: %v", err)
+	}
+
+	other := loadConfig(t, "cartpole_N12.json")
+	other.PID.Kd++
+	if other.Fingerprint() == fp {
+		t.Fatal("fingerprint unchanged after changing kd")
+	}
+	err := CheckArtifacts(dir, other)
+	if err == nil || !strings.Contains(err.Error(), "pid") {
+		t.Fatalf("changed kd: %v", err)
+	}
+	if d := Diff(sp, other); len(d) != 1 || d[0] != "pid" {
+		t.Errorf("Diff = %v, want [pid]", d)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	p := Params{LogN: 10, LogQ: []int{56}, LogP: []int{51}}
+	if err := p.Override(11, "40,40", ""); err != nil {
+		t.Fatal(err)
+	}
+	if p.LogN != 11 || len(p.LogQ) != 2 || p.LogQ[1] != 40 || p.LogP[0] != 51 {
+		t.Fatalf("got %+v", p)
+	}
+	if err := p.Override(0, "4x", ""); err == nil {
+		t.Error("bad list accepted")
+	}
+}
+
+func sameVec(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package com_utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// 스텝마다 같은 모양의 암호문을 주고받는 직렬화 (바이트 형식은 lattigo WriteTo / ReadFrom 그대로: 1 | MetaData JSON | Value)
+//
+// lattigo 는 매번 MetaData 를 JSON 으로 Marshal / Unmarshal 하고, buffer.Writer / Reader 가 아니면 bufio 를 새로 잡음
+// → 메타데이터 바이트를 한 번만 만들어 두고, 읽을 때 앞부분이 같으면 건너뛰고 Value 만 기존 다항식에 읽음
+// 메타데이터가 다르면 (처음, 상대 파라미터가 바뀜) lattigo ReadFrom 으로 읽고 그 바이트를 다시 캐시
+// 쓰는 쪽은 처음 쓸 때의 메타데이터를 계속 씀 (버퍼 암호문의 메타데이터는 바뀌지 않는다는 전제)
+type elemWire struct {
+	meta []byte
+	br   bytes.Reader
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func (ew *elemWire) writeTo(w io.Writer, ct *rlwe.Ciphertext) (int64, error) {
+	if ew.meta == nil {
+		ew.meta = []byte{0}
+		if ct.MetaData != nil {
+			m, err := ct.MetaData.MarshalBinary()
+			if err != nil {
+				ew.meta = nil
+				return 0, err
+			}
+			ew.meta = append([]byte{1}, m...)
+		}
+	}
+	if ew.w == nil {
+		ew.w = bufio.NewWriter(w)
+	} else {
+		ew.w.Reset(w)
+	}
+	n, err := ew.w.Write(ew.meta)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := ct.Value.WriteTo(ew.w)
+	if err != nil {
+		return int64(n) + m, err
+	}
+	return int64(n) + m, ew.w.Flush()
+}
+
+func (ew *elemWire) reader(p []byte) *bufio.Reader {
+	ew.br.Reset(p)
+	if ew.r == nil {
+		ew.r = bufio.NewReader(&ew.br)
+	} else {
+		ew.r.Reset(&ew.br)
+	}
+	return ew.r
+}
+
+// p 전체가 암호문 하나 (*ct 가 nil 이면 새로)
+func (ew *elemWire) unmarshal(p []byte, ct **rlwe.Ciphertext) error {
+	if *ct != nil && ew.meta != nil && bytes.HasPrefix(p, ew.meta) {
+		_, err := (*ct).Value.ReadFrom(ew.reader(p[len(ew.meta):]))
+		return err
+	}
+	if *ct == nil {
+		*ct = new(rlwe.Ciphertext)
+	}
+	n, err := (*ct).ReadFrom(ew.reader(p))
+	if err != nil {
+		ew.meta = nil
+		return err
+	}
+	// Value 앞까지가 메타데이터 바이트
+	if k := int(n) - (*ct).Value.BinarySize(); k > 0 && k <= len(p) {
+		ew.meta = append(ew.meta[:0], p[:k]...)
+	}
+	return nil
+}
+
+// 메타데이터 JSON 길이 차이 여유 (scale 등 숫자 자릿수)
+const frameSlack = 1 << 10
+
+// 파라미터로 정해지는 y (seed 압축) / u 프레임의 payload 상한 (protocol SetMaxPayload 용)
+func FrameLimits(params rlwe.Parameters) (y, u int) {
+	y = SeedSize + rlwe.NewCiphertext(params, 0, params.MaxLevel()).BinarySize() + frameSlack
+	u = rlwe.NewCiphertext(params, 1, params.MaxLevel()).BinarySize() + frameSlack
+	return y, u
+}
+
+// protocol Send / Frame.Decode 에 그대로 넘기는 암호문 (u 처럼 스텝마다 같은 모양)
+// Ct 는 보내기 전에 바꿔 끼워도 됨, 읽을 때 Ct 가 nil 이면 처음 한 번만 새로 잡음 → 이후 스텝은 할당 없음
+type CtWire struct {
+	Ct   *rlwe.Ciphertext
+	wire elemWire
+}
+
+func (cw *CtWire) BinarySize() int {
+	return cw.Ct.BinarySize()
+}
+
+func (cw *CtWire) WriteTo(w io.Writer) (int64, error) {
+	if cw.Ct == nil {
+		return 0, fmt.Errorf("ct wire: nothing to write")
+	}
+	return cw.wire.writeTo(w, cw.Ct)
+}
+
+// Frame.Decode 가 쓰는 경로 (payload 를 바로)
+func (cw *CtWire) UnmarshalBinary(p []byte) error {
+	return cw.wire.unmarshal(p, &cw.Ct)
+}
+
+func (cw *CtWire) ReadFrom(r io.Reader) (int64, error) {
+	if cw.Ct == nil {
+		cw.Ct = new(rlwe.Ciphertext)
+	}
+	cw.wire.meta = nil
+	return cw.Ct.ReadFrom(r)
+}
@@ -0,0 +1,29 @@
+package com_utils
+
+import (
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v6/core/rlwe"
+)
+
+// 실제 y / u 프레임 payload 가 FrameLimits 안에 들어가는지
+func TestFrameLimits(t *testing.T) {
+	for _, name := range []string{"cartpole_N10.json", "cartpole_N12.json"} {
+		sp, params := loadTestSpec(t, name)
+		sk := rlwe.NewKeyGenerator(params).GenSecretKeyNew()
+		codec := NewPlantCodec(params, sp.Tau(), 1, sp.Scales, sk)
+		yLimit, uLimit := FrameLimits(params)
+
+		y, err := codec.EncryptYSeeded([]float64{1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := y.BinarySize(); n > yLimit || n < yLimit/2 {
+			t.Errorf("%s: y payload %d bytes, limit %d", name, n, yLimit)
+		}
+		u := &CtWire{Ct: codec.EncryptY([]float64{1})}
+		if n := u.BinarySize(); n > uLimit || n < uLimit/2 {
+			t.Errorf("%s: u payload %d bytes, limit %d", name, n, uLimit)
+		}
+	}
+}
@@ -7,6 +7,9 @@ toolchain go1.23.6
 require (
 	github.com/CDSL-EncryptedControl/CDSL v0.0.0-20250413023419-8199ddcdedee
 	github.com/tuneinsight/lattigo/v6 v6.1.0
+	go.bug.st/serial v1.6.4
+	golang.org/x/crypto v0.32.0
+	golang.org/x/term v0.28.0
 )
 
 require (
@@ -16,8 +19,6 @@ require (
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
-	go.bug.st/serial v1.6.4 // indirect
-	golang.org/x/crypto v0.32.0 // indirect
 	golang.org/x/exp v0.0.0-20250106191152-7588d65b2ba8 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect